@@ -1,21 +1,65 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
+	"github.com/youssefM1999/report/internal/ai"
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/internal/mailer"
+	"github.com/youssefM1999/report/internal/period"
+	"github.com/youssefM1999/report/internal/repo"
+	"github.com/youssefM1999/report/internal/report"
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+const (
+	reportSubject     = "Developer Activity Report"
+	teamReportSubject = "Team Activity Report"
 )
 
 var (
 	emailFlag = &cli.StringFlag{
 		Name:  "email",
-		Usage: "The email address to send the report to",
+		Usage: "Send the report only to this address, ignoring the configured recipients",
 	}
 	rangeFlag = &cli.DurationFlag{
 		Name:  "range",
-		Usage: "The range of time to generate the report for",
+		Usage: "The range of time to generate the report for, counted forward from --since, or back from --until or now",
 		Value: 7 * 24 * time.Hour,
 	}
+	sinceFlag = &cli.StringFlag{
+		Name:  "since",
+		Usage: "Start of the report: a date (YYYY-MM-DD, from midnight in the report's timezone) or an RFC 3339 time",
+	}
+	untilFlag = &cli.StringFlag{
+		Name:  "until",
+		Usage: "End of the report: a date (YYYY-MM-DD, included) or an RFC 3339 time (excluded)",
+	}
+	periodFlag = &cli.StringFlag{
+		Name:  "period",
+		Usage: "Report on a calendar period instead: " + strings.Join(period.Presets, ", "),
+	}
+	attachMarkdownFlag = &cli.BoolFlag{
+		Name:  "attach-markdown",
+		Usage: "Attach the raw markdown report to the email",
+	}
+	attachCommitsFlag = &cli.BoolFlag{
+		Name:  "attach-commits",
+		Usage: "Attach a JSON export of the underlying commits to the email",
+	}
+	emailMembersFlag = &cli.BoolFlag{
+		Name:  "email-members",
+		Usage: "Also send every team member their own report",
+	}
+	dryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Only list what would be removed",
+	}
 )
 
 func NewApp() *cli.App {
@@ -23,16 +67,9 @@ func NewApp() *cli.App {
 		Name:  "report",
 		Usage: "Generate a report of your work",
 		Commands: []*cli.Command{
-			{
-				Name:  "generate",
-				Usage: "Generate a report of your work",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:  "email",
-						Usage: "The email address to send the report to",
-					},
-				},
-			},
+			generateReport(),
+			teamReport(),
+			cacheCommand(),
 		},
 	}
 }
@@ -44,6 +81,446 @@ func generateReport() *cli.Command {
 		Flags: []cli.Flag{
 			emailFlag,
 			rangeFlag,
+			sinceFlag,
+			untilFlag,
+			periodFlag,
+			attachMarkdownFlag,
+			attachCommitsFlag,
 		},
+		Action: runGenerate,
+	}
+}
+
+func teamReport() *cli.Command {
+	return &cli.Command{
+		Name:  "team",
+		Usage: "Generate one report covering every configured team member",
+		Flags: []cli.Flag{
+			emailFlag,
+			rangeFlag,
+			sinceFlag,
+			untilFlag,
+			periodFlag,
+			attachMarkdownFlag,
+			attachCommitsFlag,
+			emailMembersFlag,
+		},
+		Action: runTeam,
+	}
+}
+
+func cacheCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "Manage the repository cache",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "gc",
+				Usage:  "Remove the cached repositories that are no longer configured",
+				Flags:  []cli.Flag{dryRunFlag},
+				Action: runCacheGC,
+			},
+		},
+	}
+}
+
+func runCacheGC(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	dryRun := c.Bool(dryRunFlag.Name)
+	removed, skipped, err := repo.PruneCache(cfg.Repos, dryRun)
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	for _, name := range removed {
+		fmt.Fprintf(c.App.Writer, "%s %s\n", verb, filepath.Join(cfg.Repos.Dir, name))
+	}
+	for _, name := range skipped {
+		fmt.Fprintf(c.App.ErrWriter, "warning: skipped %s: not a clean clone of a cached remote, remove it by hand if it is not needed\n", filepath.Join(cfg.Repos.Dir, name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune the cache: %w", err)
+	}
+	if len(removed) == 0 {
+		fmt.Fprintf(c.App.Writer, "Nothing to remove in %s\n", cfg.Repos.Dir)
+	}
+	return nil
+}
+
+// pipeline holds what every report run needs: the loaded config, the
+// clients and the synced repositories.
+type pipeline struct {
+	cfg        config.Config
+	recipients mailer.Recipients
+	client     mailer.Client
+	summarizer ai.AI
+	rm         *repo.RepoManager
+	since      time.Time
+	until      time.Time
+	period     mailer.Period
+}
+
+// newPipeline loads the config, applies the flags and syncs every configured
+// repository once. check rejects a config the command cannot report on
+// before anything is synced, and zone picks the timezone the report window
+// is resolved in.
+func newPipeline(c *cli.Context, check func(config.Config) error, zone func(config.Config) *time.Location) (*pipeline, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := check(cfg); err != nil {
+		return nil, err
+	}
+
+	// the flag only overrides REPORT_RANGE when it was explicitly passed
+	if c.IsSet(rangeFlag.Name) {
+		cfg.Range = c.Duration(rangeFlag.Name)
+	}
+	window, label, err := reportWindow(c, cfg, time.Now().In(zone(cfg)))
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := newRecipients(cfg.Mail.Recipients)
+	if c.IsSet(emailFlag.Name) {
+		to := mailer.Recipient{Email: c.String(emailFlag.Name)}
+		if strings.EqualFold(to.Email, cfg.User.Email) {
+			to.Name = cfg.User.FullName
+		}
+		recipients = mailer.Recipients{To: []mailer.Recipient{to}}
+	}
+	if len(recipients.All()) == 0 {
+		return nil, fmt.Errorf("no recipient: set user.email or mail.to in %s or pass --%s", cfg.Repos.YamlFilePath, emailFlag.Name)
+	}
+
+	backend, err := git.NewBackend(cfg.Repos.GitBackend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create git backend: %w", err)
+	}
+	client, err := newMailer(cfg.Mail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailer: %w", err)
+	}
+	provider, err := ai.New(cfg.AI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI provider: %w", err)
+	}
+	// the weekly email should always go out, so a failing model degrades to
+	// the deterministic summary instead of aborting
+	summarizer := ai.NewFallbackAI(provider, ai.NewDeterministicAI(), func(err error) {
+		fmt.Fprintf(c.App.ErrWriter, "warning: AI summary failed, using deterministic summary: %v\n", err)
+	})
+
+	// individual repository failures are reported in the email rather than
+	// aborting the run; only give up when nothing at all could be read
+	rm := repo.NewRepoManager(cfg.Repos.Dir, cfg.Repos.Parallelism, backend)
+	if cfg.Repos.Shallow {
+		rm.LimitHistory(window.Since)
+	}
+	if err := rm.CloneAll(cfg.Repos); err != nil {
+		fmt.Fprintf(c.App.ErrWriter, "warning: some repositories could not be synced:\n%v\n", err)
+	}
+	if configured := len(rm.Statuses()); len(rm.Repos()) == 0 && configured > 0 {
+		return nil, fmt.Errorf("failed to clone repositories: none of the %d configured repositories could be synced", configured)
+	}
+
+	return &pipeline{
+		cfg:        cfg,
+		recipients: recipients,
+		client:     client,
+		summarizer: summarizer,
+		rm:         rm,
+		since:      window.Since,
+		until:      window.Until,
+		period:     label,
+	}, nil
+}
+
+// reportWindow resolves the window the report covers from the flags, as seen
+// at now: a --period preset, or --since and --until. A missing --since
+// defaults to the range before --until or now, and a missing --until to now,
+// or to the range after --since when --range is passed. Dates are read in
+// the zone of now. The returned period labels the email; only the default
+// window is labelled with its range.
+func reportWindow(c *cli.Context, cfg config.Config, now time.Time) (period.Window, mailer.Period, error) {
+	var window period.Window
+	var label mailer.Period
+	switch {
+	case c.IsSet(periodFlag.Name):
+		if c.IsSet(sinceFlag.Name) || c.IsSet(untilFlag.Name) || c.IsSet(rangeFlag.Name) {
+			return window, mailer.Period{}, fmt.Errorf("--%s cannot be combined with --%s, --%s or --%s",
+				periodFlag.Name, sinceFlag.Name, untilFlag.Name, rangeFlag.Name)
+		}
+		var err error
+		window, err = period.Preset(c.String(periodFlag.Name), now, period.Sprint(cfg.Sprint))
+		if err != nil {
+			return window, mailer.Period{}, err
+		}
+	case c.IsSet(sinceFlag.Name) || c.IsSet(untilFlag.Name):
+		if c.IsSet(sinceFlag.Name) && c.IsSet(untilFlag.Name) && c.IsSet(rangeFlag.Name) {
+			return window, mailer.Period{}, fmt.Errorf("--%s cannot be combined with both --%s and --%s",
+				rangeFlag.Name, sinceFlag.Name, untilFlag.Name)
+		}
+		window.Until = now
+		if c.IsSet(untilFlag.Name) {
+			until, err := period.ParseUntil(c.String(untilFlag.Name), now.Location())
+			if err != nil {
+				return window, mailer.Period{}, fmt.Errorf("invalid --%s: %w", untilFlag.Name, err)
+			}
+			window.Until = until
+		}
+		window.Since = window.Until.Add(-cfg.Range)
+		if c.IsSet(sinceFlag.Name) {
+			since, err := period.ParseSince(c.String(sinceFlag.Name), now.Location())
+			if err != nil {
+				return window, mailer.Period{}, fmt.Errorf("invalid --%s: %w", sinceFlag.Name, err)
+			}
+			window.Since = since
+			// an explicit range runs forward from --since
+			if !c.IsSet(untilFlag.Name) && c.IsSet(rangeFlag.Name) {
+				window.Until = since.Add(cfg.Range)
+			}
+		}
+	default:
+		window = period.Window{Since: now.Add(-cfg.Range), Until: now}
+		label.Range = cfg.Range
+	}
+	if err := window.Validate(); err != nil {
+		return window, mailer.Period{}, err
+	}
+	label.Since, label.Until = window.Since, window.Until
+	return window, label, nil
+}
+
+// checkReadable fails the run when no configured repository is left.
+func (p *pipeline) checkReadable() error {
+	if configured := len(p.rm.Statuses()); len(p.rm.Repos()) == 0 && configured > 0 {
+		return fmt.Errorf("failed to collect commits: none of the %d configured repositories could be read", configured)
+	}
+	return nil
+}
+
+// send delivers summary and prints one line per recipient reached. Like
+// repositories, a bad address only fails the run when nobody at all could
+// be reached. Dates in the email are shown in loc.
+func (p *pipeline) send(c *cli.Context, recipients mailer.Recipients, subject, summary string, loc *time.Location, attachments []mailer.Attachment) error {
+	period := p.period
+	period.Location = loc
+	results, err := p.client.Send(recipients, subject, summary, period, false, attachments...)
+	for _, result := range results {
+		fmt.Fprintf(c.App.Writer, "Report sent to %s via %s (%s, id %s, %d attempt(s))\n",
+			result.Recipient, result.Provider, result.Status, result.MessageID, result.Attempts)
+	}
+	if err != nil {
+		if len(results) == 0 {
+			return fmt.Errorf("failed to send report: %w", err)
+		}
+		fmt.Fprintf(c.App.ErrWriter, "warning: the report could not be sent to some recipients:\n%v\n", err)
+	}
+	return nil
+}
+
+func runGenerate(c *cli.Context) error {
+	checkUser := func(cfg config.Config) error {
+		if err := cfg.User.Validate(); err != nil {
+			return fmt.Errorf("no user to report on: %w in %s", err, cfg.Repos.YamlFilePath)
+		}
+		return nil
+	}
+	p, err := newPipeline(c, checkUser, func(cfg config.Config) *time.Location { return cfg.User.Location })
+	if err != nil {
+		return err
+	}
+
+	if err := p.rm.GetAllCommitsByAuthor(p.cfg.User, p.since, p.until); err != nil {
+		fmt.Fprintf(c.App.ErrWriter, "warning: some repositories could not be read:\n%v\n", err)
+	}
+	if err := p.checkReadable(); err != nil {
+		return err
+	}
+
+	rep := newReport(p.cfg.User, p.since, p.until, p.rm.Repos())
+	addFailures(rep, p.rm.Statuses())
+
+	summary, err := p.summarizer.GenerateFullReport(p.rm.Repos())
+	if err != nil {
+		return fmt.Errorf("failed to generate report: %w", err)
+	}
+	if stats := rep.StatsToMarkdown(); stats != "" {
+		summary += "\n\n" + stats
+	}
+	if failures := rep.FailuresToMarkdown(); failures != "" {
+		summary += "\n\n" + failures
+	}
+
+	attachments, err := newAttachments(c, summary, rep.ToJSON)
+	if err != nil {
+		return err
+	}
+	return p.send(c, p.recipients, reportSubject, summary, p.cfg.User.Location, attachments)
+}
+
+// runTeam reports on every team member from the same clones: one email
+// with a rollup and a section per member goes to the recipients, and with
+// --email-members each member also gets their own report. The team report
+// uses REPORT_TIMEZONE and each member's own report their timezone.
+func runTeam(c *cli.Context) error {
+	checkTeam := func(cfg config.Config) error {
+		if len(cfg.Team) == 0 {
+			return fmt.Errorf("no team members: set team in %s", cfg.Repos.YamlFilePath)
+		}
+		return nil
+	}
+	p, err := newPipeline(c, checkTeam, func(cfg config.Config) *time.Location { return cfg.Timezone })
+	if err != nil {
+		return err
+	}
+
+	team := report.NewTeamReport(p.since, p.until)
+	for _, member := range p.cfg.Team {
+		name := member.FullName
+		if name == "" {
+			name = member.Email
+		}
+		repos, failed, err := p.rm.CollectCommitsByAuthor(member, p.since, p.until)
+		if err != nil {
+			fmt.Fprintf(c.App.ErrWriter, "warning: some repositories could not be read for %s:\n%v\n", name, err)
+		}
+		summary, err := p.summarizer.GenerateFullReport(repos)
+		if err != nil {
+			return fmt.Errorf("failed to generate report for %s: %w", name, err)
+		}
+		rep := newReport(member, p.since, p.until, repos)
+		addFailures(rep, failed)
+		team.AddMember(rep, summary)
+	}
+	if err := p.checkReadable(); err != nil {
+		return err
+	}
+	addFailures(team, p.rm.Statuses())
+
+	summary := team.RollupToMarkdown() + team.MembersToMarkdown() + team.FailuresToMarkdown()
+	attachments, err := newAttachments(c, summary, team.ToJSON)
+	if err != nil {
+		return err
+	}
+	if err := p.send(c, p.recipients, teamReportSubject, summary, p.cfg.Timezone, attachments); err != nil {
+		return err
+	}
+
+	if !c.Bool(emailMembersFlag.Name) {
+		return nil
+	}
+	// a member who cannot be reached must not keep the others from getting
+	// their report
+	var errs []error
+	for _, m := range team.Members {
+		if m.Author.Email == "" {
+			continue
+		}
+		memberSummary := m.Summary
+		if stats := m.StatsToMarkdown(); stats != "" {
+			memberSummary += "\n\n" + stats
+		}
+		if failures := team.MemberFailuresToMarkdown(m); failures != "" {
+			memberSummary += "\n\n" + failures
+		}
+		attachments, err := newAttachments(c, memberSummary, m.ToJSON)
+		if err != nil {
+			return err
+		}
+		to := mailer.Recipients{To: []mailer.Recipient{{Name: m.Author.FullName, Email: m.Author.Email}}}
+		if err := p.send(c, to, reportSubject, memberSummary, m.Author.Location, attachments); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		fmt.Fprintf(c.App.ErrWriter, "warning: some members could not be sent their report:\n%v\n", err)
+	}
+	return nil
+}
+
+// newAttachments builds the attachments requested on the command line.
+// exportJSON produces the commits export.
+func newAttachments(c *cli.Context, summary string, exportJSON func() ([]byte, error)) ([]mailer.Attachment, error) {
+	var attachments []mailer.Attachment
+	if c.Bool(attachMarkdownFlag.Name) {
+		attachments = append(attachments, mailer.Attachment{
+			Filename:    "report.md",
+			ContentType: "text/markdown; charset=utf-8",
+			Data:        []byte(summary),
+		})
+	}
+	if c.Bool(attachCommitsFlag.Name) {
+		commitsJSON, err := exportJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to export commits: %w", err)
+		}
+		attachments = append(attachments, mailer.Attachment{
+			Filename:    "commits.json",
+			ContentType: "application/json",
+			Data:        commitsJSON,
+		})
+	}
+	return attachments, nil
+}
+
+func newRecipients(cfg config.RecipientsConfig) mailer.Recipients {
+	convert := func(list []config.RecipientConfig) []mailer.Recipient {
+		recipients := make([]mailer.Recipient, len(list))
+		for i, r := range list {
+			recipients[i] = mailer.Recipient(r)
+		}
+		return recipients
+	}
+	return mailer.Recipients{
+		To:  convert(cfg.To),
+		CC:  convert(cfg.CC),
+		BCC: convert(cfg.BCC),
+	}
+}
+
+func newMailer(cfg config.MailConfig) (mailer.Client, error) {
+	switch cfg.Provider {
+	case "", mailer.ProviderSendGrid:
+		return mailer.NewSendGridMailer(mailer.FromEmail, cfg.APIKey), nil
+	case mailer.ProviderSMTP:
+		return mailer.NewSMTPMailer(mailer.SMTPConfig(cfg.SMTP)), nil
+	case mailer.ProviderOutbox:
+		return mailer.NewOutboxMailer(cfg.OutboxDir, ""), nil
+	default:
+		return nil, fmt.Errorf("unknown mail provider %q (want %q, %q or %q)",
+			cfg.Provider, mailer.ProviderSendGrid, mailer.ProviderSMTP, mailer.ProviderOutbox)
+	}
+}
+
+// newReport builds the author's report from the collected repositories.
+func newReport(author config.UserConfig, since, until time.Time, repos []*repo.Repo) *report.Report {
+	rep := report.NewReport(author, since, until)
+	for _, r := range repos {
+		commits := make([]repo.Commit, len(r.Commits))
+		for i, c := range r.Commits {
+			commits[i] = *c
+		}
+		rep.AddRepoCommits(r.Name, commits)
+	}
+	return rep
+}
+
+// failureRecorder is implemented by both report.Report and report.TeamReport.
+type failureRecorder interface {
+	AddFailure(repoName, url, reason string)
+}
+
+// addFailures records every repository that failed along the way.
+func addFailures(rep failureRecorder, statuses []repo.RepoStatus) {
+	for _, status := range statuses {
+		if status.Failed() {
+			rep.AddFailure(status.Name, status.URL, status.Err.Error())
+		}
 	}
 }
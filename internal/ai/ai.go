@@ -2,12 +2,15 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/youssefM1999/report/internal/repo"
+	"github.com/youssefM1999/report/pkg/retry"
 )
 
 const systemPrompt = `You are a technical writer creating concise developer activity reports. Your task is to summarize git commits into clear, actionable bullet points.
@@ -66,76 +69,67 @@ type AI interface {
 	GenerateFullReport(repos []*repo.Repo) (string, error)
 }
 
+const (
+	repoReportMaxTokens = 1024
+	fullReportMaxTokens = 2048
+)
+
 type ClaudeAI struct {
-	client anthropic.Client
+	tokenBudget
+	client    anthropic.Client
+	model     anthropic.Model
+	maxTokens int
+	policy    retry.Policy
 }
 
-func NewClaudeAI(apiKey string) *ClaudeAI {
+// NewClaudeAI disables the SDK's own retries so that requests follow the
+// same retry policy as the other providers.
+func NewClaudeAI(apiKey string, opts ...option.RequestOption) *ClaudeAI {
 	client := anthropic.NewClient(
-		option.WithAPIKey(apiKey),
+		append([]option.RequestOption{option.WithAPIKey(apiKey), option.WithMaxRetries(0)}, opts...)...,
 	)
 	return &ClaudeAI{
 		client: client,
+		model:  anthropic.ModelClaude3_5HaikuLatest,
+		policy: defaultRetryPolicy(),
 	}
 }
 
 func (c *ClaudeAI) GenerateRepoReport(repoName string, commits []*repo.Commit) (string, error) {
-	if len(commits) == 0 {
-		return "No commits in this period.", nil
-	}
+	return generateRepoReport(c, repoName, commits)
+}
 
-	commitsText := formatCommitsForPrompt(commits)
-	userPrompt := fmt.Sprintf(userPromptTemplate, repoName, commitsText)
+func (c *ClaudeAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
+	return generateFullReport(c, repos)
+}
 
-	message, err := c.client.Messages.New(context.Background(), anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaude3_5HaikuLatest,
-		MaxTokens: 1024,
+func (c *ClaudeAI) complete(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	params := anthropic.MessageNewParams{
+		Model:     c.model,
+		MaxTokens: int64(tokenLimit(c.maxTokens, maxTokens)),
 		System: []anthropic.TextBlockParam{
-			{Text: systemPrompt},
+			{Text: system},
 		},
 		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
+			anthropic.NewUserMessage(anthropic.NewTextBlock(user)),
 		},
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to generate report: %w", err)
 	}
 
-	var result strings.Builder
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			result.WriteString(block.Text)
+	var message *anthropic.Message
+	err := retry.Do(ctx, c.policy, func(ctx context.Context) error {
+		var err error
+		message, err = c.client.Messages.New(ctx, params)
+		var apiErr *anthropic.Error
+		if errors.As(err, &apiErr) && apiErr.Response != nil {
+			return &claudeError{
+				err:        apiErr,
+				retryAfter: retry.ParseRetryAfter(apiErr.Response.Header.Get("Retry-After"), time.Now()),
+			}
 		}
-	}
-
-	return result.String(), nil
-}
-
-func (c *ClaudeAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
-	// Check if there are any commits across all repos
-	totalCommits := 0
-	for _, r := range repos {
-		totalCommits += len(r.Commits)
-	}
-	if totalCommits == 0 {
-		return "No commits across any repositories in this period.", nil
-	}
-
-	reposText := formatAllReposForPrompt(repos)
-	userPrompt := fmt.Sprintf(multiRepoUserPromptTemplate, reposText)
-
-	message, err := c.client.Messages.New(context.Background(), anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaude3_5HaikuLatest,
-		MaxTokens: 2048,
-		System: []anthropic.TextBlockParam{
-			{Text: multiRepoSystemPrompt},
-		},
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
-		},
+		return err
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to generate report: %w", err)
+		return "", err
 	}
 
 	var result strings.Builder
@@ -153,8 +147,20 @@ func formatCommitsForPrompt(commits []*repo.Commit) string {
 	for _, c := range commits {
 		sb.WriteString(fmt.Sprintf("Commit: %s\n", c.Hash))
 		sb.WriteString(fmt.Sprintf("Author: %s\n", c.Author))
-		sb.WriteString(fmt.Sprintf("Date: %s\n", c.Date.Format("2006-01-02 15:04:05")))
+		sb.WriteString(fmt.Sprintf("Date: %s\n", c.Date.Format("2006-01-02 15:04:05 -07:00")))
 		sb.WriteString(fmt.Sprintf("Message: %s\n", c.Message))
+		if c.Body != "" {
+			sb.WriteString(fmt.Sprintf("Description:\n%s\n", c.Body))
+		}
+		for _, t := range c.Trailers {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", t.Key, t.Value))
+		}
+		if c.CoAuthored {
+			sb.WriteString("Role: co-author (the user paired on this commit but is not its author)\n")
+		}
+		if !c.OnMainBranch && len(c.Branches) > 0 {
+			sb.WriteString(fmt.Sprintf("Branches: %s (not merged yet)\n", strings.Join(c.Branches, ", ")))
+		}
 		if len(c.Content) > 0 {
 			sb.WriteString(fmt.Sprintf("Changes:\n%s\n", c.Content))
 		}
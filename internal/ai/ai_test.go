@@ -49,7 +49,7 @@ func TestFullReportGenerationFlow(t *testing.T) {
 	}
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	if err := r.GetCommitsByAuthor(author, since); err != nil {
+	if err := r.GetCommitsByAuthor(author, since, time.Time{}); err != nil {
 		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
 	}
 	t.Logf("Found %d commits", len(r.Commits))
@@ -105,7 +105,7 @@ func TestMultiRepoReportGenerationFlow(t *testing.T) {
 	defer os.RemoveAll(tmpDir)
 
 	// Setup RepoManager with multiple repos
-	rm := repo.NewRepoManager(tmpDir)
+	rm := repo.NewRepoManager(tmpDir, 2, nil)
 
 	reposConfig := config.ReposConfig{
 		TargetRepos: []config.RepoConfig{
@@ -136,7 +136,7 @@ func TestMultiRepoReportGenerationFlow(t *testing.T) {
 	}
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	if err := rm.GetAllCommitsByAuthor(author, since); err != nil {
+	if err := rm.GetAllCommitsByAuthor(author, since, time.Time{}); err != nil {
 		t.Fatalf("GetAllCommitsByAuthor() failed: %v", err)
 	}
 
@@ -237,6 +237,30 @@ func TestFormatCommitsForPrompt(t *testing.T) {
 	t.Log(result)
 }
 
+func TestFormatCommitsForPrompt_BodyAndCoAuthor(t *testing.T) {
+	commits := []*repo.Commit{{
+		Hash:       "abc1234567890",
+		Author:     "Other Dev",
+		Date:       time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		Message:    "Fix login redirect loop",
+		Body:       "The session cookie was dropped on redirect.",
+		Trailers:   []repo.Trailer{{Key: "Co-authored-by", Value: "Jane Doe <jane@example.com>"}},
+		CoAuthored: true,
+	}}
+
+	result := formatCommitsForPrompt(commits)
+
+	for _, want := range []string{
+		"Description:\nThe session cookie was dropped on redirect.\n",
+		"Co-authored-by: Jane Doe <jane@example.com>\n",
+		"Role: co-author",
+	} {
+		if !contains(result, want) {
+			t.Errorf("prompt should contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
 }
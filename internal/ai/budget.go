@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+const (
+	defaultContextTokens = 100000
+	defaultDiffTokens    = 2000
+	// charsPerToken is a deliberately conservative estimate for English text
+	// and source code; it keeps prompts under the limit without a tokenizer.
+	charsPerToken = 4
+)
+
+const mergeSystemPrompt = `You are a technical writer combining partial developer activity summaries into one report. The partial summaries were produced from consecutive batches of commits of the same repository.
+
+Guidelines:
+- Keep every commit hash that appears in the partial summaries
+- Merge sections that describe the same feature or fix
+- Keep the existing markdown structure: one "### <brief title> - <commit hash>" section per commit or group of commits, with 2-3 bullet points each
+- Do not add a repository heading`
+
+const mergeUserPromptTemplate = `Combine the following partial summaries of repository "%s" into a single report:
+
+%s`
+
+// tokenBudget bounds the size of the prompts sent to a provider. Providers
+// embed it so that the shared generation code can read their limits.
+type tokenBudget struct {
+	contextTokens int // max input tokens per request
+	diffTokens    int // max tokens kept from a single commit diff
+}
+
+func (b tokenBudget) budget() tokenBudget {
+	return tokenBudget{
+		contextTokens: tokenLimit(b.contextTokens, defaultContextTokens),
+		diffTokens:    tokenLimit(b.diffTokens, defaultDiffTokens),
+	}
+}
+
+func estimateTokens(s string) int {
+	return (len(s) + charsPerToken - 1) / charsPerToken
+}
+
+// prepareCommits returns copies of commits whose diffs are cut down to
+// diffTokens, leaving the originals untouched.
+func prepareCommits(commits []*repo.Commit, diffTokens int) []*repo.Commit {
+	prepared := make([]*repo.Commit, len(commits))
+	for i, c := range commits {
+		cp := *c
+		cp.Content = truncateDiff(c.Content, c.Stats, diffTokens)
+		prepared[i] = &cp
+	}
+	return prepared
+}
+
+// truncateDiff keeps the head of a diff that is larger than maxTokens and
+// replaces the rest with a summary of what was dropped, taken from the
+// commit's stats, so the model still knows which files the commit touched.
+func truncateDiff(diff string, stats repo.CommitStats, maxTokens int) string {
+	if estimateTokens(diff) <= maxTokens {
+		return diff
+	}
+
+	var note strings.Builder
+	note.WriteString(fmt.Sprintf("\n[diff truncated: %d files changed, +%d/-%d lines in total]\n",
+		stats.FilesChanged, stats.Insertions, stats.Deletions))
+	if len(stats.Files) > 0 {
+		paths := make([]string, len(stats.Files))
+		for i, f := range stats.Files {
+			paths[i] = f.Path
+		}
+		note.WriteString(fmt.Sprintf("[files: %s]\n", strings.Join(paths, ", ")))
+	}
+
+	keep := maxTokens*charsPerToken - note.Len()
+	if keep < 0 {
+		keep = 0
+	}
+	head := diff[:keep]
+	if i := strings.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	} else {
+		head = strings.ToValidUTF8(head, "")
+	}
+	return head + note.String()
+}
+
+// batchCommits splits commits into consecutive batches whose formatted
+// prompt text fits maxTokens. A commit that is too large on its own still
+// gets a batch of its own.
+func batchCommits(commits []*repo.Commit, maxTokens int) [][]*repo.Commit {
+	var batches [][]*repo.Commit
+	var current []*repo.Commit
+	size := 0
+	for _, c := range commits {
+		tokens := estimateTokens(formatCommitsForPrompt([]*repo.Commit{c}))
+		if len(current) > 0 && size+tokens > maxTokens {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, c)
+		size += tokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// promptTokens is the space left for commit data once the fixed parts of a
+// prompt are accounted for.
+func promptTokens(b tokenBudget, system, template string) int {
+	available := b.contextTokens - estimateTokens(system) - estimateTokens(template)
+	if available < 1 {
+		return 1
+	}
+	return available
+}
+
+// mapReduceRepoReport summarises each batch of commits separately and then
+// merges the partial summaries until a single report remains.
+func mapReduceRepoReport(ctx context.Context, c completer, repoName string, batches [][]*repo.Commit) (string, error) {
+	partials := make([]string, len(batches))
+	for i, batch := range batches {
+		userPrompt := fmt.Sprintf(userPromptTemplate, repoName, formatCommitsForPrompt(batch))
+		partial, err := c.complete(ctx, systemPrompt, userPrompt, repoReportMaxTokens)
+		if err != nil {
+			return "", fmt.Errorf("batch %d/%d: %w", i+1, len(batches), err)
+		}
+		partials[i] = partial
+	}
+
+	b := c.budget()
+	available := promptTokens(b, mergeSystemPrompt, mergeUserPromptTemplate)
+	for len(partials) > 1 {
+		var merged []string
+		for _, group := range groupSummaries(partials, available) {
+			if len(group) == 1 {
+				merged = append(merged, group[0])
+				continue
+			}
+			userPrompt := fmt.Sprintf(mergeUserPromptTemplate, repoName, strings.Join(group, "\n\n---\n\n"))
+			result, err := c.complete(ctx, mergeSystemPrompt, userPrompt, repoReportMaxTokens)
+			if err != nil {
+				return "", fmt.Errorf("merge: %w", err)
+			}
+			merged = append(merged, result)
+		}
+		partials = merged
+	}
+	return partials[0], nil
+}
+
+// groupSummaries packs summaries into groups that fit maxTokens. Every group
+// holds at least two summaries when possible so that merging always makes
+// progress.
+func groupSummaries(summaries []string, maxTokens int) [][]string {
+	var groups [][]string
+	var current []string
+	size := 0
+	for _, s := range summaries {
+		tokens := estimateTokens(s)
+		if len(current) > 1 && size+tokens > maxTokens {
+			groups = append(groups, current)
+			current, size = nil, 0
+		}
+		current = append(current, s)
+		size += tokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
@@ -0,0 +1,187 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+type call struct {
+	system string
+	user   string
+}
+
+// recordingCompleter answers every call with a numbered summary and keeps
+// the prompts it was sent.
+type recordingCompleter struct {
+	tokenBudget
+	calls []call
+}
+
+func (r *recordingCompleter) complete(_ context.Context, system, user string, _ int) (string, error) {
+	r.calls = append(r.calls, call{system: system, user: user})
+	return fmt.Sprintf("summary %d", len(r.calls)), nil
+}
+
+func bigCommits(n, diffLines int) []*repo.Commit {
+	commits := make([]*repo.Commit, n)
+	for i := range commits {
+		var diff strings.Builder
+		diff.WriteString(fmt.Sprintf("diff --git a/file%d.go b/file%d.go\n@@ -1 +1 @@\n", i, i))
+		for j := range diffLines {
+			diff.WriteString(fmt.Sprintf("+line %d of a fairly long change\n", j))
+		}
+		commits[i] = &repo.Commit{
+			Hash:    fmt.Sprintf("%07d000", i),
+			Author:  "Test Author",
+			Date:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			Message: fmt.Sprintf("Change %d", i),
+			Content: diff.String(),
+			Stats: repo.CommitStats{
+				FilesChanged: 1,
+				Insertions:   diffLines,
+				Files:        []repo.FileStat{{Path: fmt.Sprintf("file%d.go", i), Insertions: diffLines}},
+			},
+		}
+	}
+	return commits
+}
+
+func TestTruncateDiff(t *testing.T) {
+	commit := bigCommits(1, 500)[0]
+	diff := commit.Content
+
+	if got := truncateDiff(diff, commit.Stats, estimateTokens(diff)); got != diff {
+		t.Error("diff within budget should be unchanged")
+	}
+
+	got := truncateDiff(diff, commit.Stats, 200)
+	if estimateTokens(got) > 200 {
+		t.Errorf("truncated diff uses %d tokens, want <= 200", estimateTokens(got))
+	}
+	if !strings.HasPrefix(got, "diff --git a/file0.go b/file0.go\n") {
+		t.Error("truncated diff should keep its head")
+	}
+	if !strings.Contains(got, "[diff truncated: 1 files changed, +500/-0 lines in total]") {
+		t.Errorf("truncated diff should summarise what was dropped, got tail:\n%s", got[len(got)-200:])
+	}
+}
+
+func TestPrepareCommits_DoesNotMutate(t *testing.T) {
+	commits := bigCommits(2, 500)
+	original := commits[0].Content
+
+	prepared := prepareCommits(commits, 100)
+	if commits[0].Content != original {
+		t.Error("prepareCommits() should not modify the original commits")
+	}
+	if len(prepared[0].Content) >= len(original) {
+		t.Error("prepareCommits() should truncate large diffs")
+	}
+}
+
+func TestBatchCommits(t *testing.T) {
+	commits := bigCommits(10, 20)
+	perCommit := estimateTokens(formatCommitsForPrompt(commits[:1]))
+
+	batches := batchCommits(commits, perCommit*3)
+	if len(batches) != 4 {
+		t.Fatalf("Expected 4 batches, got %d", len(batches))
+	}
+	seen := 0
+	for _, b := range batches {
+		for _, c := range b {
+			if c != commits[seen] {
+				t.Fatalf("batches should keep commit order")
+			}
+			seen++
+		}
+	}
+
+	if batches := batchCommits(commits[:1], 1); len(batches) != 1 {
+		t.Errorf("an oversized commit should get its own batch, got %d batches", len(batches))
+	}
+}
+
+func TestGenerateRepoReport_SingleCallWhenItFits(t *testing.T) {
+	c := &recordingCompleter{}
+
+	report, err := generateRepoReport(c, "repo", bigCommits(3, 5))
+	if err != nil {
+		t.Fatalf("generateRepoReport() failed: %v", err)
+	}
+	if len(c.calls) != 1 || report != "summary 1" {
+		t.Errorf("Expected a single call, got %d calls and report %q", len(c.calls), report)
+	}
+}
+
+func TestGenerateRepoReport_MapReduce(t *testing.T) {
+	commits := bigCommits(12, 40)
+	c := &recordingCompleter{tokenBudget: tokenBudget{contextTokens: 2500, diffTokens: 300}}
+
+	report, err := generateRepoReport(c, "repo", commits)
+	if err != nil {
+		t.Fatalf("generateRepoReport() failed: %v", err)
+	}
+
+	var mapCalls, mergeCalls int
+	for _, call := range c.calls {
+		if estimateTokens(call.system+call.user) > c.budget().contextTokens {
+			t.Errorf("prompt of %d tokens exceeds the budget", estimateTokens(call.system+call.user))
+		}
+		switch call.system {
+		case systemPrompt:
+			mapCalls++
+		case mergeSystemPrompt:
+			mergeCalls++
+		}
+	}
+	if mapCalls < 2 {
+		t.Errorf("Expected several batch calls, got %d", mapCalls)
+	}
+	if mergeCalls == 0 {
+		t.Error("Expected the partial summaries to be merged")
+	}
+	if last := c.calls[len(c.calls)-1]; last.system != mergeSystemPrompt || report != fmt.Sprintf("summary %d", len(c.calls)) {
+		t.Errorf("report should be the result of the final merge, got %q", report)
+	}
+	for i := range commits {
+		found := false
+		for _, call := range c.calls {
+			if call.system == systemPrompt && strings.Contains(call.user, commits[i].Hash) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("commit %s was not sent in any batch", commits[i].Hash)
+		}
+	}
+}
+
+func TestGenerateFullReport_SplitsPerRepoWhenTooLarge(t *testing.T) {
+	repos := []*repo.Repo{
+		{Name: "alpha", Commits: bigCommits(4, 40)},
+		{Name: "beta", Commits: bigCommits(4, 40)},
+		{Name: "gamma"},
+	}
+	c := &recordingCompleter{tokenBudget: tokenBudget{contextTokens: 1500, diffTokens: 300}}
+
+	report, err := generateFullReport(c, repos)
+	if err != nil {
+		t.Fatalf("generateFullReport() failed: %v", err)
+	}
+	for _, call := range c.calls {
+		if call.system == multiRepoSystemPrompt {
+			t.Error("an oversized full report should not be sent in one call")
+		}
+	}
+	for _, want := range []string{"## alpha\n\nsummary", "## beta\n\nsummary", "## gamma\n\nNo commits in this period."} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q, got:\n%s", want, report)
+		}
+	}
+}
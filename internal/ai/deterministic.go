@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+// DeterministicAI builds a report purely from commit metadata and diff
+// statistics. It needs no network access and always produces the same
+// output for the same commits, which makes it a safe fallback when no model
+// is available.
+type DeterministicAI struct{}
+
+func NewDeterministicAI() *DeterministicAI {
+	return &DeterministicAI{}
+}
+
+func (d *DeterministicAI) GenerateRepoReport(repoName string, commits []*repo.Commit) (string, error) {
+	if len(commits) == 0 {
+		return "No commits in this period.", nil
+	}
+	return summarizeCommits(commits), nil
+}
+
+func (d *DeterministicAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
+	totalCommits := 0
+	for _, r := range repos {
+		totalCommits += len(r.Commits)
+	}
+	if totalCommits == 0 {
+		return "No commits across any repositories in this period.", nil
+	}
+
+	var sb strings.Builder
+	for _, r := range repos {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", r.Name))
+		if len(r.Commits) == 0 {
+			sb.WriteString("No commits in this period.\n\n")
+			continue
+		}
+		sb.WriteString(summarizeCommits(r.Commits))
+	}
+	return sb.String(), nil
+}
+
+// commitGroups lists the conventional commit types in the order they are
+// rendered. Commits that do not follow the convention end up in "Other".
+var commitGroups = []struct {
+	types []string
+	title string
+}{
+	{[]string{"feat"}, "Features"},
+	{[]string{"fix"}, "Fixes"},
+	{[]string{"perf"}, "Performance"},
+	{[]string{"refactor"}, "Refactoring"},
+	{[]string{"docs"}, "Documentation"},
+	{[]string{"test"}, "Tests"},
+	{[]string{"build", "ci"}, "Build & CI"},
+	{[]string{"chore", "style", "revert"}, "Maintenance"},
+}
+
+const otherGroup = "Other"
+
+var (
+	conventionalCommitRegex = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?!?:\s*(.+)$`)
+	ticketRegex             = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b|#\d+\b`)
+)
+
+type commitSummary struct {
+	commit  *repo.Commit
+	group   string
+	subject string
+	tickets []string
+}
+
+func summarizeCommits(commits []*repo.Commit) string {
+	grouped := map[string][]commitSummary{}
+	var insertions, deletions int
+	fileTouches := map[string]int{}
+	tickets := map[string]bool{}
+
+	for _, c := range commits {
+		s := summarizeCommit(c)
+		grouped[s.group] = append(grouped[s.group], s)
+		insertions += c.Stats.Insertions
+		deletions += c.Stats.Deletions
+		for _, f := range c.Stats.Files {
+			fileTouches[f.Path]++
+		}
+		for _, t := range s.tickets {
+			tickets[t] = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("*%d commits • %d files changed • +%d / -%d lines*\n\n",
+		len(commits), len(fileTouches), insertions, deletions))
+
+	titles := make([]string, 0, len(commitGroups)+1)
+	for _, g := range commitGroups {
+		titles = append(titles, g.title)
+	}
+	titles = append(titles, otherGroup)
+
+	for _, title := range titles {
+		group := grouped[title]
+		if len(group) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### %s\n", title))
+		for _, s := range group {
+			sb.WriteString(fmt.Sprintf("- **%s** - `%s` (%d files, +%d/-%d)",
+				s.subject, shortHash(s.commit.Hash), s.commit.Stats.FilesChanged, s.commit.Stats.Insertions, s.commit.Stats.Deletions))
+			if len(s.tickets) > 0 {
+				sb.WriteString(" " + strings.Join(s.tickets, ", "))
+			}
+			if s.commit.CoAuthored {
+				sb.WriteString(" _(co-author)_")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(tickets) > 0 {
+		sb.WriteString(fmt.Sprintf("**Tickets:** %s\n\n", strings.Join(sortedKeys(tickets), ", ")))
+	}
+	if files := mostTouchedFiles(fileTouches, 5); len(files) > 0 {
+		sb.WriteString(fmt.Sprintf("**Most touched files:** %s\n\n", strings.Join(files, ", ")))
+	}
+
+	return sb.String()
+}
+
+func summarizeCommit(c *repo.Commit) commitSummary {
+	s := commitSummary{
+		commit:  c,
+		group:   otherGroup,
+		subject: c.Message,
+		tickets: uniqueMatches(ticketRegex, c.Message+"\n"+c.Body),
+	}
+
+	if m := conventionalCommitRegex.FindStringSubmatch(c.Message); m != nil {
+		commitType := strings.ToLower(m[1])
+		for _, g := range commitGroups {
+			for _, t := range g.types {
+				if t == commitType {
+					s.group = g.title
+					s.subject = m[2]
+				}
+			}
+		}
+	}
+	return s
+}
+
+func uniqueMatches(re *regexp.Regexp, s string) []string {
+	seen := map[string]bool{}
+	var matches []string
+	for _, m := range re.FindAllString(s, -1) {
+		if !seen[m] {
+			seen[m] = true
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func mostTouchedFiles(touches map[string]int, limit int) []string {
+	files := sortedKeys(touches)
+	sort.SliceStable(files, func(i, j int) bool {
+		return touches[files[i]] > touches[files[j]]
+	})
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+// sampleStats is what git diff --numstat reports for a commit touching two
+// files.
+var sampleStats = repo.CommitStats{
+	FilesChanged: 2,
+	Insertions:   3,
+	Deletions:    2,
+	Files: []repo.FileStat{
+		{Path: "internal/mailer/mailer.go", Insertions: 2, Deletions: 1},
+		{Path: "README.md", Insertions: 1, Deletions: 1},
+	},
+}
+
+func deterministicRepos() []*repo.Repo {
+	return []*repo.Repo{
+		{
+			Name: "report",
+			Commits: []*repo.Commit{
+				{Hash: "aaaaaaa1111", Message: "feat(mailer): retry more often (PROJ-12)", Date: time.Now(), Stats: sampleStats},
+				{Hash: "bbbbbbb2222", Message: "fix: handle nil response, closes #45", Date: time.Now()},
+				{Hash: "ccccccc3333", Message: "Update dependencies", Date: time.Now()},
+			},
+		},
+		{Name: "empty"},
+	}
+}
+
+func TestDeterministicAI_GenerateFullReport(t *testing.T) {
+	report, err := NewDeterministicAI().GenerateFullReport(deterministicRepos())
+	if err != nil {
+		t.Fatalf("GenerateFullReport() failed: %v", err)
+	}
+
+	expected := []string{
+		"## report",
+		"*3 commits • 2 files changed • +3 / -2 lines*",
+		"### Features\n- **retry more often (PROJ-12)** - `aaaaaaa` (2 files, +3/-2) PROJ-12",
+		"### Fixes\n- **handle nil response, closes #45** - `bbbbbbb` (0 files, +0/-0) #45",
+		"### Other\n- **Update dependencies** - `ccccccc`",
+		"**Tickets:** #45, PROJ-12",
+		"**Most touched files:** README.md, internal/mailer/mailer.go",
+		"## empty\n\nNo commits in this period.",
+	}
+	for _, want := range expected {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q\ngot:\n%s", want, report)
+		}
+	}
+
+	again, _ := NewDeterministicAI().GenerateFullReport(deterministicRepos())
+	if again != report {
+		t.Error("GenerateFullReport() should be deterministic")
+	}
+}
+
+func TestDeterministicAI_NoCommits(t *testing.T) {
+	d := NewDeterministicAI()
+
+	report, _ := d.GenerateRepoReport("repo", nil)
+	if report != "No commits in this period." {
+		t.Errorf("GenerateRepoReport() = %q", report)
+	}
+	report, _ = d.GenerateFullReport([]*repo.Repo{{Name: "repo"}})
+	if report != "No commits across any repositories in this period." {
+		t.Errorf("GenerateFullReport() = %q", report)
+	}
+}
+
+type failingAI struct{}
+
+func (failingAI) GenerateRepoReport(string, []*repo.Commit) (string, error) {
+	return "", errors.New("api down")
+}
+
+func (failingAI) GenerateFullReport([]*repo.Repo) (string, error) {
+	return "", errors.New("api down")
+}
+
+func TestFallbackAI(t *testing.T) {
+	var notified []error
+	f := NewFallbackAI(failingAI{}, NewDeterministicAI(), func(err error) {
+		notified = append(notified, err)
+	})
+
+	report, err := f.GenerateFullReport(deterministicRepos())
+	if err != nil {
+		t.Fatalf("GenerateFullReport() should fall back, got: %v", err)
+	}
+	if !strings.Contains(report, "## report") {
+		t.Errorf("expected deterministic report, got:\n%s", report)
+	}
+
+	if _, err := f.GenerateRepoReport("report", deterministicRepos()[0].Commits); err != nil {
+		t.Fatalf("GenerateRepoReport() should fall back, got: %v", err)
+	}
+	if len(notified) != 2 {
+		t.Errorf("expected 2 fallback notifications, got %d", len(notified))
+	}
+}
@@ -0,0 +1,46 @@
+package ai
+
+import (
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+// FallbackAI asks primary first and, if it fails, answers with fallback
+// instead so that a report is always produced. onFallback, when set, is told
+// about every primary failure.
+type FallbackAI struct {
+	primary    AI
+	fallback   AI
+	onFallback func(err error)
+}
+
+func NewFallbackAI(primary, fallback AI, onFallback func(err error)) *FallbackAI {
+	return &FallbackAI{
+		primary:    primary,
+		fallback:   fallback,
+		onFallback: onFallback,
+	}
+}
+
+func (f *FallbackAI) GenerateRepoReport(repoName string, commits []*repo.Commit) (string, error) {
+	report, err := f.primary.GenerateRepoReport(repoName, commits)
+	if err == nil {
+		return report, nil
+	}
+	f.notify(err)
+	return f.fallback.GenerateRepoReport(repoName, commits)
+}
+
+func (f *FallbackAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
+	report, err := f.primary.GenerateFullReport(repos)
+	if err == nil {
+		return report, nil
+	}
+	f.notify(err)
+	return f.fallback.GenerateFullReport(repos)
+}
+
+func (f *FallbackAI) notify(err error) {
+	if f.onFallback != nil {
+		f.onFallback(err)
+	}
+}
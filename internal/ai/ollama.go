@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/repo"
+	"github.com/youssefM1999/report/pkg/retry"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "llama3.1"
+)
+
+// OllamaAI talks to a local Ollama-style server through its /api/chat
+// endpoint, so commit data never leaves the machine.
+type OllamaAI struct {
+	tokenBudget
+	baseURL   string
+	model     string
+	maxTokens int
+	client    *http.Client
+	policy    retry.Policy
+}
+
+func NewOllamaAI(baseURL, model string, maxTokens int) *OllamaAI {
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	return &OllamaAI{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		model:     model,
+		maxTokens: maxTokens,
+		client:    http.DefaultClient,
+		policy:    defaultRetryPolicy(),
+	}
+}
+
+func (o *OllamaAI) GenerateRepoReport(repoName string, commits []*repo.Commit) (string, error) {
+	return generateRepoReport(o, repoName, commits)
+}
+
+func (o *OllamaAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
+	return generateFullReport(o, repos)
+}
+
+type ollamaOptions struct {
+	NumPredict int `json:"num_predict"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message chatMessage `json:"message"`
+}
+
+func (o *OllamaAI) complete(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	req := ollamaRequest{
+		Model: o.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Options: ollamaOptions{NumPredict: tokenLimit(o.maxTokens, maxTokens)},
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, o.client, o.policy, o.baseURL+"/api/chat", nil, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.Message.Content, nil
+}
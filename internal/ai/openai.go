@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/repo"
+	"github.com/youssefM1999/report/pkg/retry"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAI talks to any server implementing the OpenAI chat completions API,
+// including OpenAI itself, Azure-style gateways, vLLM and LiteLLM.
+type OpenAI struct {
+	tokenBudget
+	baseURL   string
+	apiKey    string
+	model     string
+	maxTokens int
+	client    *http.Client
+	policy    retry.Policy
+}
+
+func NewOpenAI(baseURL, apiKey, model string, maxTokens int) *OpenAI {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	return &OpenAI{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		maxTokens: maxTokens,
+		client:    http.DefaultClient,
+		policy:    defaultRetryPolicy(),
+	}
+}
+
+func (o *OpenAI) GenerateRepoReport(repoName string, commits []*repo.Commit) (string, error) {
+	return generateRepoReport(o, repoName, commits)
+}
+
+func (o *OpenAI) GenerateFullReport(repos []*repo.Repo) (string, error) {
+	return generateFullReport(o, repos)
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (o *OpenAI) complete(ctx context.Context, system, user string, maxTokens int) (string, error) {
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+
+	req := openAIRequest{
+		Model: o.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		MaxTokens: tokenLimit(o.maxTokens, maxTokens),
+	}
+
+	var resp openAIResponse
+	if err := postJSON(ctx, o.client, o.policy, o.baseURL+"/chat/completions", headers, req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("chat completion returned no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
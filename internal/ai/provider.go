@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/internal/repo"
+	"github.com/youssefM1999/report/pkg/retry"
+)
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+	ProviderOllama    = "ollama"
+	// ProviderNone skips language models entirely and uses DeterministicAI.
+	ProviderNone = "none"
+)
+
+// completer is the single call every provider has to implement: send a
+// system and user prompt and return the generated text. budget reports how
+// much input the provider accepts per call.
+type completer interface {
+	complete(ctx context.Context, system, user string, maxTokens int) (string, error)
+	budget() tokenBudget
+}
+
+// New returns the AI implementation selected by cfg.Provider. Anthropic
+// without an API key cannot work, so it falls back to DeterministicAI.
+func New(cfg config.AIConfig) (AI, error) {
+	switch cfg.Provider {
+	case "", ProviderAnthropic:
+		if cfg.Key == "" {
+			return NewDeterministicAI(), nil
+		}
+		var opts []option.RequestOption
+		if cfg.BaseURL != "" {
+			opts = append(opts, option.WithBaseURL(cfg.BaseURL))
+		}
+		c := NewClaudeAI(cfg.Key, opts...)
+		if cfg.Model != "" {
+			c.model = anthropic.Model(cfg.Model)
+		}
+		c.maxTokens = cfg.MaxTokens
+		c.tokenBudget = newTokenBudget(cfg)
+		return c, nil
+	case ProviderOpenAI:
+		o := NewOpenAI(cfg.BaseURL, cfg.Key, cfg.Model, cfg.MaxTokens)
+		o.tokenBudget = newTokenBudget(cfg)
+		return o, nil
+	case ProviderOllama:
+		o := NewOllamaAI(cfg.BaseURL, cfg.Model, cfg.MaxTokens)
+		o.tokenBudget = newTokenBudget(cfg)
+		return o, nil
+	case ProviderNone:
+		return NewDeterministicAI(), nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q (want %q, %q, %q or %q)",
+			cfg.Provider, ProviderAnthropic, ProviderOpenAI, ProviderOllama, ProviderNone)
+	}
+}
+
+func newTokenBudget(cfg config.AIConfig) tokenBudget {
+	return tokenBudget{
+		contextTokens: cfg.ContextTokens,
+		diffTokens:    cfg.DiffTokens,
+	}
+}
+
+// reportTimeout bounds the calls summarising one repository, or all of them
+// at once, retries and their delays included.
+const reportTimeout = 10 * time.Minute
+
+// generateRepoReport summarises one repository in a single call when the
+// commits fit the provider's budget, and map-reduces over batches otherwise.
+func generateRepoReport(c completer, repoName string, commits []*repo.Commit) (string, error) {
+	if len(commits) == 0 {
+		return "No commits in this period.", nil
+	}
+
+	b := c.budget()
+	commits = prepareCommits(commits, b.diffTokens)
+	batches := batchCommits(commits, promptTokens(b, systemPrompt, userPromptTemplate))
+
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+	result, err := mapReduceRepoReport(ctx, c, repoName, batches)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate report: %w", err)
+	}
+	return result, nil
+}
+
+// generateFullReport summarises every repository in one call when possible.
+// When the commits do not fit, each repository is summarised on its own and
+// the results are stitched together under per-repo headings.
+func generateFullReport(c completer, repos []*repo.Repo) (string, error) {
+	// Check if there are any commits across all repos
+	totalCommits := 0
+	for _, r := range repos {
+		totalCommits += len(r.Commits)
+	}
+	if totalCommits == 0 {
+		return "No commits across any repositories in this period.", nil
+	}
+
+	b := c.budget()
+	prepared := make([]*repo.Repo, len(repos))
+	for i, r := range repos {
+		cp := *r
+		cp.Commits = prepareCommits(r.Commits, b.diffTokens)
+		prepared[i] = &cp
+	}
+
+	reposText := formatAllReposForPrompt(prepared)
+	if estimateTokens(reposText) <= promptTokens(b, multiRepoSystemPrompt, multiRepoUserPromptTemplate) {
+		userPrompt := fmt.Sprintf(multiRepoUserPromptTemplate, reposText)
+		ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+		defer cancel()
+		result, err := c.complete(ctx, multiRepoSystemPrompt, userPrompt, fullReportMaxTokens)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate report: %w", err)
+		}
+		return result, nil
+	}
+
+	var sb strings.Builder
+	for _, r := range prepared {
+		summary, err := generateRepoReport(c, r.Name, r.Commits)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", r.Name, err)
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n%s\n\n", r.Name, strings.TrimSpace(summary)))
+	}
+	return sb.String(), nil
+}
+
+// tokenLimit returns the configured output limit, or the call's default when
+// none was configured.
+func tokenLimit(configured, fallback int) int {
+	if configured > 0 {
+		return configured
+	}
+	return fallback
+}
+
+// postJSON sends body as JSON to url and decodes a 2xx JSON response into
+// out, retrying transient failures according to policy. Any other status is
+// returned as a *statusError carrying the response body.
+func postJSON(ctx context.Context, client *http.Client, policy retry.Policy, url string, headers map[string]string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return retry.Do(ctx, policy, func(ctx context.Context) error {
+		return postJSONOnce(ctx, client, url, headers, payload, out)
+	})
+}
+
+func postJSONOnce(ctx context.Context, client *http.Client, url string, headers map[string]string, payload []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{
+			URL:        url,
+			Status:     resp.Status,
+			StatusCode: resp.StatusCode,
+			Body:       string(bytes.TrimSpace(respBody)),
+			retryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return retry.Permanent(fmt.Errorf("failed to decode response from %s: %w", url, err))
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+func testRepos() []*repo.Repo {
+	return []*repo.Repo{
+		{
+			Name: "repo1",
+			Commits: []*repo.Commit{
+				{
+					Hash:    "abc1234567890",
+					Author:  "Test Author",
+					Date:    time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+					Message: "Add new feature",
+				},
+			},
+		},
+	}
+}
+
+func TestOpenAI_GenerateFullReport(t *testing.T) {
+	var got openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want %q", auth, "Bearer test-key")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"## repo1\n- summary"}}]}`))
+	}))
+	defer server.Close()
+
+	ai := NewOpenAI(server.URL+"/v1/", "test-key", "test-model", 512)
+
+	report, err := ai.GenerateFullReport(testRepos())
+	if err != nil {
+		t.Fatalf("GenerateFullReport() failed: %v", err)
+	}
+	if report != "## repo1\n- summary" {
+		t.Errorf("report = %q", report)
+	}
+
+	if got.Model != "test-model" {
+		t.Errorf("model = %q, want %q", got.Model, "test-model")
+	}
+	if got.MaxTokens != 512 {
+		t.Errorf("max_tokens = %d, want 512", got.MaxTokens)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Role != "user" {
+		t.Fatalf("unexpected messages: %+v", got.Messages)
+	}
+	if !strings.Contains(got.Messages[1].Content, "abc1234567890") {
+		t.Error("user prompt should contain the commit hash")
+	}
+}
+
+func TestOpenAI_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAI(server.URL, "bad-key", "", 0).GenerateFullReport(testRepos())
+	if err == nil {
+		t.Fatal("GenerateFullReport() should fail on 401")
+	}
+	if !strings.Contains(err.Error(), "invalid api key") {
+		t.Errorf("error should carry the response body, got: %v", err)
+	}
+	var se *statusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized {
+		t.Errorf("error should wrap the 401 response, got: %v", err)
+	}
+}
+
+func TestOpenAI_RetriesTransientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	ai := NewOpenAI(server.URL, "key", "", 0)
+	ai.policy.BaseDelay = time.Millisecond
+
+	report, err := ai.GenerateRepoReport("repo1", testRepos()[0].Commits)
+	if err != nil {
+		t.Fatalf("GenerateRepoReport() failed: %v", err)
+	}
+	if report != "done" || calls.Load() != 3 {
+		t.Errorf("report = %q after %d calls, want %q after 3", report, calls.Load(), "done")
+	}
+}
+
+func TestOpenAI_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ai := NewOpenAI(server.URL, "key", "", 0)
+	ai.policy.BaseDelay = time.Millisecond
+
+	if _, err := ai.GenerateRepoReport("repo1", testRepos()[0].Commits); err == nil {
+		t.Fatal("GenerateRepoReport() should fail on 400")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("a 400 should not be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestClaudeAI_RetriesRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"summary"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	ai := NewClaudeAI("key", option.WithBaseURL(server.URL))
+	ai.policy.BaseDelay = time.Millisecond
+
+	report, err := ai.GenerateRepoReport("repo1", testRepos()[0].Commits)
+	if err != nil {
+		t.Fatalf("GenerateRepoReport() failed: %v", err)
+	}
+	if report != "summary" || calls.Load() != 2 {
+		t.Errorf("report = %q after %d calls, want %q after 2", report, calls.Load(), "summary")
+	}
+}
+
+func TestOllamaAI_GenerateRepoReport(t *testing.T) {
+	var got ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"llama3.1","message":{"role":"assistant","content":"### Add new feature - abc1234"},"done":true}`))
+	}))
+	defer server.Close()
+
+	ai := NewOllamaAI(server.URL, "", 0)
+
+	report, err := ai.GenerateRepoReport("repo1", testRepos()[0].Commits)
+	if err != nil {
+		t.Fatalf("GenerateRepoReport() failed: %v", err)
+	}
+	if report != "### Add new feature - abc1234" {
+		t.Errorf("report = %q", report)
+	}
+
+	if got.Model != defaultOllamaModel {
+		t.Errorf("model = %q, want %q", got.Model, defaultOllamaModel)
+	}
+	if got.Stream {
+		t.Error("stream should be disabled")
+	}
+	if got.Options.NumPredict != repoReportMaxTokens {
+		t.Errorf("num_predict = %d, want %d", got.Options.NumPredict, repoReportMaxTokens)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{"", "*ai.ClaudeAI", false},
+		{ProviderNone, "*ai.DeterministicAI", false},
+		{ProviderAnthropic, "*ai.ClaudeAI", false},
+		{ProviderOpenAI, "*ai.OpenAI", false},
+		{ProviderOllama, "*ai.OllamaAI", false},
+		{"bard", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := New(config.AIConfig{Provider: tt.provider, Key: "key", Model: "m", MaxTokens: 100})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q) error = %v, wantErr %v", tt.provider, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if name := fmt.Sprintf("%T", got); name != tt.want {
+				t.Errorf("New(%q) = %s, want %s", tt.provider, name, tt.want)
+			}
+		}
+	}
+}
+
+func TestNew_AnthropicWithoutKeyIsDeterministic(t *testing.T) {
+	got, err := New(config.AIConfig{Provider: ProviderAnthropic})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	if _, ok := got.(*DeterministicAI); !ok {
+		t.Errorf("New() without a key = %T, want *ai.DeterministicAI", got)
+	}
+}
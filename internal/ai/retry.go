@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/youssefM1999/report/pkg/retry"
+)
+
+// defaultRetryPolicy retries rate limits and server errors; anything the
+// provider rejects outright (bad key, bad request) fails at once.
+func defaultRetryPolicy() retry.Policy {
+	policy := retry.DefaultPolicy()
+	policy.Retryable = retryable
+	return policy
+}
+
+// statusError is a non-2xx response from a provider API.
+type statusError struct {
+	URL        string
+	Status     string
+	StatusCode int
+	Body       string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned %s: %s", e.URL, e.Status, e.Body)
+}
+
+func (e *statusError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// claudeError carries the Retry-After hint of a failed Anthropic request.
+type claudeError struct {
+	err        *anthropic.Error
+	retryAfter time.Duration
+}
+
+func (e *claudeError) Error() string             { return e.err.Error() }
+func (e *claudeError) Unwrap() error             { return e.err }
+func (e *claudeError) RetryAfter() time.Duration { return e.retryAfter }
+
+// retryable classifies provider errors for the retry policy. Errors without
+// a status code are network failures and worth another attempt.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return retry.RetryableStatus(se.StatusCode)
+	}
+	var ae *anthropic.Error
+	if errors.As(err, &ae) {
+		return retry.RetryableStatus(ae.StatusCode)
+	}
+	return true
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"time"
@@ -8,6 +10,7 @@ import (
 	"github.com/goccy/go-yaml"
 	"github.com/youssefM1999/report/internal/env"
 	"github.com/youssefM1999/report/pkg/filesystem"
+	"github.com/youssefM1999/report/pkg/git"
 )
 
 type Config struct {
@@ -17,28 +20,128 @@ type Config struct {
 	Logger LoggerConfig
 	AI     AIConfig
 	User   UserConfig
+	Team   []UserConfig // members covered by team reports
 	Range  time.Duration
+
+	// Timezone sets the day boundaries of dates and period presets for
+	// users without a timezone of their own and for team reports, and
+	// Sprint the sprints of the sprint presets.
+	Timezone *time.Location
+	Sprint   SprintConfig
+}
+
+// SprintConfig describes sprints of Days days, one of which starts on the
+// date of Anchor.
+type SprintConfig struct {
+	Days   int
+	Anchor time.Time
 }
 
 type UserConfig struct {
-	FullName string `yaml:"full_name"`
-	Email    string `yaml:"email"`
+	FullName string `yaml:"full_name" json:"full_name"`
+	Email    string `yaml:"email" json:"email"`
+
+	// Other identities the user commits under, e.g. a personal or GitHub
+	// noreply address or a previous name. Patterns are POSIX extended
+	// regular expressions, as git log takes them, matched against
+	// "Name <email>"; Perl-style syntax such as \d or (?:...) is rejected.
+	// All matching is case-insensitive and applies after the repository's
+	// .mailmap.
+	Emails   []string `yaml:"emails" json:"emails,omitempty"`
+	Names    []string `yaml:"names" json:"names,omitempty"`
+	Patterns []string `yaml:"patterns" json:"patterns,omitempty"`
+
+	// Timezone is the IANA name of the zone the user's dates are shown in,
+	// such as "Asia/Tokyo"; REPORT_TIMEZONE is used when it is empty.
+	// Location is the loaded zone.
+	Timezone string         `yaml:"timezone" json:"timezone,omitempty"`
+	Location *time.Location `yaml:"-" json:"-"`
 }
 
 type MailConfig struct {
-	APIKey string
+	Provider   string // "sendgrid", "smtp" or "outbox"
+	APIKey     string
+	SMTP       SMTPConfig
+	OutboxDir  string // where the outbox provider writes messages
+	Recipients RecipientsConfig
+}
+
+// RecipientsConfig lists who receives the report. When To is empty in the
+// YAML file the report goes to the configured user.
+type RecipientsConfig struct {
+	To  []RecipientConfig `yaml:"to"`
+	CC  []RecipientConfig `yaml:"cc"`
+	BCC []RecipientConfig `yaml:"bcc"`
+}
+
+type RecipientConfig struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Security string // "starttls", "tls" or "none"
+	Auth     string // "plain", "login" or "none"
 }
 
 type ReposConfig struct {
 	YamlFilePath string //path to the yaml definition file
 	Dir          string
 	TargetRepos  []RepoConfig
+	Scan         []ScanConfig // folders searched for local repositories
+	Parallelism  int          // max git operations run at once
+	GitBackend   string       // "exec" (git binary) or "go-git"
+
+	// Filter makes every cache a partial clone, such as "blob:none", and
+	// Shallow only fetches the history the report range needs. Both are
+	// ignored by the go-git backend. Filter cannot be combined with auth
+	// settings.
+	Filter  string
+	Shallow bool
+}
+
+// ScanConfig discovers every git working copy under Root and reads them in
+// place, like repositories configured with a path.
+type ScanConfig struct {
+	Root     string   `yaml:"root"`
+	MaxDepth int      `yaml:"max_depth"` // directory levels searched below Root; 0 uses the default
+	Exclude  []string `yaml:"exclude"`   // path.Match globs on paths relative to Root
+}
+
+// AuthConfig holds the credentials for private remotes. The HTTPS token is
+// read from an environment variable or a file so that it stays out of the
+// YAML file. Git never prompts: missing or rejected credentials fail the
+// repository.
+type AuthConfig struct {
+	Username   string `yaml:"username"`    // sent with the token, defaults to "x-access-token"
+	TokenEnv   string `yaml:"token_env"`   // environment variable holding the token
+	TokenFile  string `yaml:"token_file"`  // file holding the token
+	SSHKey     string `yaml:"ssh_key"`     // private key for SSH remotes
+	KnownHosts string `yaml:"known_hosts"` // known_hosts file instead of the user's one
 }
 
 type RepoConfig struct {
 	Name   string `yaml:"name"`
 	URL    string `yaml:"url"`
-	Branch string `yaml:"branch"`
+	Branch string `yaml:"branch"` // main branch, the one always fetched
+
+	// Path reads an existing local checkout in place instead of cloning
+	// URL. It is never pulled and its checked out branch never changes;
+	// Branch, when set, names a local branch read without checking it out,
+	// otherwise HEAD is read.
+	Path string `yaml:"path"`
+
+	// Branches selects remote branches to scan in addition to Branch, as
+	// path.Match globs such as "feature/*"; a lone "*" selects them all.
+	Branches []string `yaml:"branches"`
+
+	// Auth overrides the top-level auth settings for this repository.
+	Auth *AuthConfig `yaml:"auth"`
 }
 
 type LoggerConfig struct {
@@ -47,13 +150,26 @@ type LoggerConfig struct {
 }
 
 type AIConfig struct {
-	Key string
+	Provider  string // "anthropic", "openai", "ollama" or "none"
+	Key       string
+	BaseURL   string
+	Model     string
+	MaxTokens int // 0 keeps each call's default
+
+	// ContextTokens bounds the input of a single request and DiffTokens the
+	// part of it one commit diff may take; 0 uses the built-in defaults.
+	ContextTokens int
+	DiffTokens    int
 }
 
 // yamlFileConfig represents the structure of the YAML configuration file
 type yamlFileConfig struct {
-	User  UserConfig   `yaml:"user"`
-	Repos []RepoConfig `yaml:"repos"`
+	User  UserConfig       `yaml:"user"`
+	Team  []UserConfig     `yaml:"team"`
+	Mail  RecipientsConfig `yaml:"mail"`
+	Auth  AuthConfig       `yaml:"auth"`
+	Repos []RepoConfig     `yaml:"repos"`
+	Scan  []ScanConfig     `yaml:"scan"`
 }
 
 func Load() (Config, error) {
@@ -70,45 +186,211 @@ func Load() (Config, error) {
 		return Config{}, err
 	}
 
-	aiKey := env.GetString("ANTHROPIC_API_KEY", "")
+	aiKey := env.GetString("AI_API_KEY", env.GetString("ANTHROPIC_API_KEY", ""))
 
 	sendgridAPIKey := env.GetString("SENDGRID_API_KEY", "")
 
+	outboxDir := env.GetString("MAIL_OUTBOX_DIR", "outbox")
+	outboxDir, err = filesystem.ResolvePath(outboxDir)
+	if err != nil {
+		return Config{}, err
+	}
+
 	repoDir := env.GetString("REPO_DIR", "repos")
 	repoDir, err = filesystem.ResolvePath(repoDir)
 	if err != nil {
 		return Config{}, err
 	}
 
+	timezone, err := time.LoadLocation(env.GetString("REPORT_TIMEZONE", "Local"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid REPORT_TIMEZONE: %w", err)
+	}
+
+	var sprintAnchor time.Time
+	if anchor := env.GetString("SPRINT_ANCHOR", ""); anchor != "" {
+		sprintAnchor, err = time.ParseInLocation("2006-01-02", anchor, timezone)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid SPRINT_ANCHOR %q: want YYYY-MM-DD", anchor)
+		}
+	}
+
 	yamlFilePath := env.GetString("YAML_FILE_PATH", "config.yaml")
 	yamlConfig, err := loadYAMLConfig(yamlFilePath)
 	if err != nil {
 		return Config{}, err
 	}
 
+	if err := validateRepos(yamlConfig.Repos); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: %w", yamlFilePath, err)
+	}
+	// team reports need no user of their own
+	if user := yamlConfig.User; user.FullName != "" || user.Validate() != ErrNoIdentity {
+		if err := user.Validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid %s: user: %w", yamlFilePath, err)
+		}
+	}
+	for i, member := range yamlConfig.Team {
+		if err := member.Validate(); err != nil {
+			return Config{}, fmt.Errorf("invalid %s: team[%d]: %w", yamlFilePath, i, err)
+		}
+	}
+	if err := loadLocation(&yamlConfig.User, timezone); err != nil {
+		return Config{}, fmt.Errorf("invalid %s: user: %w", yamlFilePath, err)
+	}
+	for i := range yamlConfig.Team {
+		if err := loadLocation(&yamlConfig.Team[i], timezone); err != nil {
+			return Config{}, fmt.Errorf("invalid %s: team[%d]: %w", yamlFilePath, i, err)
+		}
+	}
+
+	// repositories without auth settings of their own use the top-level ones
+	for i := range yamlConfig.Repos {
+		if yamlConfig.Repos[i].Auth == nil {
+			yamlConfig.Repos[i].Auth = &yamlConfig.Auth
+		}
+	}
+	filter := env.GetString("REPO_FILTER", "")
+	if filter != "" {
+		// a partial clone fetches missing objects whenever git reads them,
+		// and those fetches cannot be given the credentials
+		for _, r := range yamlConfig.Repos {
+			if r.URL != "" && *r.Auth != (AuthConfig{}) {
+				return Config{}, fmt.Errorf("REPO_FILTER cannot be used with repositories that need auth settings, such as %q", r.Name)
+			}
+		}
+	}
+
+	recipients := yamlConfig.Mail
+	if len(recipients.To) == 0 && yamlConfig.User.Email != "" {
+		recipients.To = []RecipientConfig{{Name: yamlConfig.User.FullName, Email: yamlConfig.User.Email}}
+	}
+
 	config := Config{
 		Env: e,
 		Logger: LoggerConfig{
 			Dir: logDir,
 		},
 		AI: AIConfig{
-			Key: aiKey,
+			Provider:      env.GetString("AI_PROVIDER", "anthropic"),
+			Key:           aiKey,
+			BaseURL:       env.GetString("AI_BASE_URL", ""),
+			Model:         env.GetString("AI_MODEL", ""),
+			MaxTokens:     env.GetInt("AI_MAX_TOKENS", 0),
+			ContextTokens: env.GetInt("AI_CONTEXT_TOKENS", 0),
+			DiffTokens:    env.GetInt("AI_DIFF_TOKENS", 0),
 		},
 		Mail: MailConfig{
-			APIKey: sendgridAPIKey,
+			Provider: env.GetString("MAIL_PROVIDER", "sendgrid"),
+			APIKey:   sendgridAPIKey,
+			SMTP: SMTPConfig{
+				Host:     env.GetString("SMTP_HOST", ""),
+				Port:     env.GetInt("SMTP_PORT", 587),
+				Username: env.GetString("SMTP_USERNAME", ""),
+				Password: env.GetString("SMTP_PASSWORD", ""),
+				From:     env.GetString("SMTP_FROM", ""),
+				Security: env.GetString("SMTP_SECURITY", "starttls"),
+				Auth:     env.GetString("SMTP_AUTH", ""),
+			},
+			OutboxDir:  outboxDir,
+			Recipients: recipients,
 		},
 		Repos: ReposConfig{
 			Dir:          repoDir,
 			TargetRepos:  yamlConfig.Repos,
+			Scan:         yamlConfig.Scan,
 			YamlFilePath: yamlFilePath,
+			Parallelism:  env.GetInt("REPO_PARALLELISM", 4),
+			GitBackend:   env.GetString("GIT_BACKEND", "exec"),
+			Filter:       filter,
+			Shallow:      env.GetBool("REPO_SHALLOW", false),
+		},
+		User:     yamlConfig.User,
+		Team:     yamlConfig.Team,
+		Range:    env.GetDuration("REPORT_RANGE", 7*24*time.Hour),
+		Timezone: timezone,
+		Sprint: SprintConfig{
+			Days:   env.GetInt("SPRINT_DAYS", 14),
+			Anchor: sprintAnchor,
 		},
-		User:  yamlConfig.User,
-		Range: env.GetDuration("REPORT_RANGE", 7*24*time.Hour),
 	}
 
 	return config, nil
 }
 
+// validateRepos checks that every repository is either cloned from a URL
+// or read from a local path, and that no name or repository is configured
+// twice.
+func validateRepos(repos []RepoConfig) error {
+	names := map[string]int{}
+	caches := map[string]int{}
+	for i, r := range repos {
+		if j, ok := names[r.Name]; ok && r.Name != "" {
+			return fmt.Errorf("repos[%d] %q: name already used by repos[%d]", i, r.Name, j)
+		}
+		names[r.Name] = i
+		if r.URL != "" {
+			// URLs of the same repository share its cache
+			cache := git.CacheDir(r.URL)
+			if j, ok := caches[cache]; ok {
+				return fmt.Errorf("repos[%d] %q: same repository as repos[%d] %q", i, r.Name, j, repos[j].Name)
+			}
+			caches[cache] = i
+		}
+
+		switch {
+		case r.URL == "" && r.Path == "":
+			return fmt.Errorf("repos[%d] %q: set url or path", i, r.Name)
+		case r.URL != "" && r.Path != "":
+			return fmt.Errorf("repos[%d] %q: set either url or path, not both", i, r.Name)
+		case r.URL != "" && r.Name == "":
+			return fmt.Errorf("repos[%d]: name is required with url", i)
+		case r.URL != "" && r.Branch == "":
+			return fmt.Errorf("repos[%d] %q: branch is required with url", i, r.Name)
+		}
+	}
+	return nil
+}
+
+// ErrNoIdentity is returned for users with nothing to match commits with.
+var ErrNoIdentity = errors.New("set email, emails, names or patterns to match commits with")
+
+// Validate checks that the user has an identity to match commits with and
+// that their patterns compile. The full name is only used for display.
+func (u UserConfig) Validate() error {
+	if u.Email == "" && len(u.Emails) == 0 && len(u.Names) == 0 && len(u.Patterns) == 0 {
+		return ErrNoIdentity
+	}
+	for _, pattern := range u.Patterns {
+		if err := validatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePattern checks an identity pattern the way both git backends read
+// it.
+func validatePattern(pattern string) error {
+	_, err := git.CompileAuthorPattern(pattern)
+	return err
+}
+
+// loadLocation sets the user's Location from their Timezone, or to fallback
+// when they have none.
+func loadLocation(user *UserConfig, fallback *time.Location) error {
+	if user.Timezone == "" {
+		user.Location = fallback
+		return nil
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return fmt.Errorf("timezone: %w", err)
+	}
+	user.Location = loc
+	return nil
+}
+
 func loadYAMLConfig(yamlFilePath string) (yamlFileConfig, error) {
 	rootDir, err := filesystem.FindModuleRoot()
 	if err != nil {
@@ -2,8 +2,12 @@ package mailer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/gomarkdown/markdown"
@@ -17,24 +21,121 @@ const (
 	MaxRetries = 3
 )
 
+// Client delivers the report. Every recipient gets an individually
+// personalised copy; a failure for one recipient does not stop delivery to
+// the others, and the returned error joins all per-recipient failures.
 type Client interface {
-	Send(email, username, subject, markdownContent string, period time.Duration, isSandbox bool) error
+	Send(recipients Recipients, subject, markdownContent string, period Period, isSandbox bool, attachments ...Attachment) ([]SendResult, error)
+}
+
+type Recipient struct {
+	Name  string
+	Email string
+}
+
+// Recipients lists who receives the report. To and CC recipients appear in
+// the message headers, BCC recipients never do.
+type Recipients struct {
+	To  []Recipient
+	CC  []Recipient
+	BCC []Recipient
+}
+
+// All returns every recipient once, in To, CC, BCC order. An address listed
+// more than once keeps its first position.
+func (r Recipients) All() []Recipient {
+	seen := map[string]bool{}
+	var all []Recipient
+	for _, list := range [][]Recipient{r.To, r.CC, r.BCC} {
+		for _, rcpt := range list {
+			key := strings.ToLower(rcpt.Email)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			all = append(all, rcpt)
+		}
+	}
+	return all
+}
+
+// RecipientError ties a delivery failure to its recipient.
+type RecipientError struct {
+	Recipient string
+	Err       error
+}
+
+func (e *RecipientError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Recipient, e.Err)
+}
+
+func (e *RecipientError) Unwrap() error {
+	return e.Err
+}
+
+// sendEach calls send for every recipient and collects the results of the
+// successful deliveries. Failures are joined as RecipientErrors.
+func sendEach(recipients Recipients, send func(rcpt Recipient) (SendResult, error)) ([]SendResult, error) {
+	all := recipients.All()
+	if len(all) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	var results []SendResult
+	var errs []error
+	for _, rcpt := range all {
+		result, err := send(rcpt)
+		if err != nil {
+			errs = append(errs, &RecipientError{Recipient: rcpt.Email, Err: err})
+			continue
+		}
+		result.Recipient = rcpt.Email
+		results = append(results, result)
+	}
+	return results, errors.Join(errs...)
+}
+
+// Attachment is a file sent alongside the report.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// SendResult describes what a backend did with a message.
+type SendResult struct {
+	Recipient string // address the message was delivered to
+	Provider  string // backend that handled the message, e.g. "sendgrid"
+	MessageID string // provider message ID, or the Message-ID header
+	Status    string // provider status, e.g. "202 Accepted"
+	Attempts  int    // number of delivery attempts made
 }
 
 type EmailData struct {
 	Subject     string
+	Greeting    string
 	HTMLContent template.HTML
+	TextContent string
 	Period      string
 	GeneratedAt string
 }
 
-func NewEmailData(subject, markdownContent string, period time.Duration) EmailData {
+func NewEmailData(subject, markdownContent string, period Period) EmailData {
 	return EmailData{
 		Subject:     subject,
 		HTMLContent: template.HTML(markdownToHTML(markdownContent)),
-		Period:      formatPeriod(period),
-		GeneratedAt: time.Now().Format("January 2, 2006 at 3:04 PM"),
+		TextContent: markdownToText(markdownContent),
+		Period:      period.String(),
+		GeneratedAt: time.Now().In(period.location()).Format("January 2, 2006 at 3:04 PM MST"),
+	}
+}
+
+// greeting personalises the opening line for a recipient.
+func greeting(name string) string {
+	if name == "" {
+		return "Hi,"
 	}
+	return fmt.Sprintf("Hi %s,", name)
 }
 
 func markdownToHTML(md string) string {
@@ -49,6 +150,73 @@ func markdownToHTML(md string) string {
 	return string(markdown.Render(doc, renderer))
 }
 
+var (
+	markdownLinkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+	markdownEmphasisRegex = regexp.MustCompile("\\*\\*|__|`")
+)
+
+// markdownToText turns the markdown report into readable plain text for the
+// text/plain alternative: headings are underlined, emphasis and code marks
+// are dropped and links are spelled out.
+func markdownToText(md string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(md), "\n") {
+		line = markdownLinkRegex.ReplaceAllString(line, "$1 ($2)")
+		line = markdownEmphasisRegex.ReplaceAllString(line, "")
+
+		switch {
+		case strings.HasPrefix(line, "# "), strings.HasPrefix(line, "## "):
+			title := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			underline := "-"
+			if strings.HasPrefix(line, "# ") {
+				underline = "="
+			}
+			sb.WriteString(title + "\n" + strings.Repeat(underline, len([]rune(title))) + "\n")
+		case strings.HasPrefix(line, "#"):
+			sb.WriteString(strings.TrimSpace(strings.TrimLeft(line, "#")) + "\n")
+		case strings.TrimSpace(line) == "---":
+			sb.WriteString(strings.Repeat("-", 40) + "\n")
+		case strings.HasPrefix(line, "* "):
+			sb.WriteString("- " + line[2:] + "\n")
+		default:
+			sb.WriteString(line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Period is the window a report covers. A window counted back from the time
+// the report is generated sets Range and reads like "Past Week"; any other
+// window reads as its first and last day in Location. Location also sets
+// the zone of the email's generation time; nil uses the local one.
+type Period struct {
+	Since    time.Time
+	Until    time.Time // exclusive
+	Range    time.Duration
+	Location *time.Location
+}
+
+func (p Period) String() string {
+	if p.Range > 0 {
+		return formatPeriod(p.Range)
+	}
+	first := p.Since.In(p.location()).Format(periodDateFormat)
+	last := p.Until.Add(-time.Nanosecond).In(p.location()).Format(periodDateFormat)
+	if first == last {
+		return first
+	}
+	return first + " - " + last
+}
+
+const periodDateFormat = "Jan 2, 2006"
+
+func (p Period) location() *time.Location {
+	if p.Location == nil {
+		return time.Local
+	}
+	return p.Location
+}
+
 func formatPeriod(d time.Duration) string {
 	days := int(d.Hours() / 24)
 	switch days {
@@ -68,6 +236,7 @@ const emailTemplate = `<!DOCTYPE html>
 <head>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Subject}}</title>
     <style>
         h2 { color: #1a1a1a; font-size: 18px; margin-top: 25px; margin-bottom: 12px; padding-bottom: 6px; border-bottom: 1px solid #e1e5e9; }
         h3 { color: #2c5282; font-size: 15px; margin-top: 18px; margin-bottom: 8px; }
@@ -79,11 +248,12 @@ const emailTemplate = `<!DOCTYPE html>
 <body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; line-height: 1.6; color: #333; max-width: 800px; margin: 0 auto; padding: 20px;">
 
     <div style="border-bottom: 2px solid #2c5282; padding-bottom: 15px; margin-bottom: 25px;">
-        <h1 style="margin: 0; font-size: 24px; font-weight: 600; color: #1a1a1a;">Developer Activity Report</h1>
+        <h1 style="margin: 0; font-size: 24px; font-weight: 600; color: #1a1a1a;">{{.Subject}}</h1>
         <p style="margin: 5px 0 0 0; color: #666; font-size: 14px;">{{.Period}} • Generated {{.GeneratedAt}}</p>
     </div>
 
     <div style="font-size: 15px;">
+        {{if .Greeting}}<p>{{.Greeting}}</p>{{end}}
         {{.HTMLContent}}
     </div>
 
@@ -94,6 +264,31 @@ const emailTemplate = `<!DOCTYPE html>
 </body>
 </html>`
 
+const textEmailTemplate = `{{.Subject}}
+{{.Period}} • Generated {{.GeneratedAt}}
+{{if .Greeting}}
+{{.Greeting}}
+{{end}}
+{{.TextContent}}
+--
+This report was automatically generated. Feel free to edit before forwarding.
+`
+
+// renderTextTemplate renders the plain-text alternative of the email.
+func renderTextTemplate(data EmailData) (string, error) {
+	tmpl, err := texttemplate.New("email-text").Parse(textEmailTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
 func renderEmailTemplate(data EmailData) (string, error) {
 	// Add some inline styles for the markdown-generated HTML
 	styledTemplate := emailTemplate
@@ -15,7 +15,7 @@ func TestNewEmailData(t *testing.T) {
 - Added migration to create user_invitations table
 - Implemented email activation mechanism
 `
-	period := 7 * 24 * time.Hour
+	period := Period{Range: 7 * 24 * time.Hour}
 
 	data := NewEmailData(subject, markdown, period)
 
@@ -33,6 +33,13 @@ func TestNewEmailData(t *testing.T) {
 	}
 }
 
+func TestNewEmailData_GeneratedAtInLocation(t *testing.T) {
+	data := NewEmailData("Report", "", Period{Range: 24 * time.Hour, Location: time.FixedZone("JST", 9*60*60)})
+	if !strings.HasSuffix(data.GeneratedAt, " JST") {
+		t.Errorf("GeneratedAt = %q, want a time in JST", data.GeneratedAt)
+	}
+}
+
 func TestRenderEmailTemplate(t *testing.T) {
 	markdownReport := `## social
 
@@ -51,7 +58,7 @@ func TestRenderEmailTemplate(t *testing.T) {
 No commits in this period.
 `
 
-	data := NewEmailData("Developer Activity Report", markdownReport, 7*24*time.Hour)
+	data := NewEmailData("Developer Activity Report", markdownReport, Period{Range: 7 * 24 * time.Hour})
 
 	html, err := renderEmailTemplate(data)
 	if err != nil {
@@ -69,8 +76,8 @@ No commits in this period.
 	if len(html) == 0 {
 		t.Error("HTML output is empty")
 	}
-	if !strings.Contains(html, "Developer Activity Report") {
-		t.Error("HTML should contain title")
+	if !strings.Contains(html, "<title>Developer Activity Report</title>") || !strings.Contains(html, ">Developer Activity Report</h1>") {
+		t.Error("HTML should be titled with the subject")
 	}
 	if !strings.Contains(html, "8ae1b21") {
 		t.Error("HTML should contain commit hash")
@@ -124,3 +131,69 @@ func TestFormatPeriod(t *testing.T) {
 		}
 	}
 }
+
+func TestPeriod_String(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	tokyo := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		period   Period
+		expected string
+	}{
+		{Period{Since: day(3, 1), Until: day(3, 8), Range: 7 * 24 * time.Hour}, "Past Week"},
+		// the end is exclusive
+		{Period{Since: day(2, 26), Until: day(3, 11), Location: time.UTC}, "Feb 26, 2024 - Mar 10, 2024"},
+		{Period{Since: day(3, 1), Until: day(3, 1).Add(15 * time.Hour), Location: time.UTC}, "Mar 1, 2024"},
+		// UTC midnights are 9am in Tokyo
+		{Period{Since: day(2, 26), Until: day(3, 11), Location: tokyo}, "Feb 26, 2024 - Mar 11, 2024"},
+	}
+	for _, tt := range tests {
+		if got := tt.period.String(); got != tt.expected {
+			t.Errorf("%+v.String() = %q, want %q", tt.period, got, tt.expected)
+		}
+	}
+}
+
+func TestMarkdownToText(t *testing.T) {
+	md := `# Work Report
+
+## social
+
+### 8ae1b21 - **Implement** User Validation
+- Added ` + "`user_invitations`" + ` table
+* See [the docs](https://example.com/docs)
+
+---
+`
+	text := markdownToText(md)
+
+	expected := []string{
+		"Work Report\n===========\n",
+		"social\n------\n",
+		"8ae1b21 - Implement User Validation\n",
+		"- Added user_invitations table\n",
+		"- See the docs (https://example.com/docs)\n",
+		strings.Repeat("-", 40),
+	}
+	for _, want := range expected {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q\ngot:\n%s", want, text)
+		}
+	}
+	if strings.ContainsAny(text, "#*`") {
+		t.Errorf("text should not contain markdown syntax:\n%s", text)
+	}
+}
+
+func TestRenderTextTemplate(t *testing.T) {
+	data := NewEmailData("Weekly Report", "## social\n- Fixed it\n", Period{Range: 7 * 24 * time.Hour})
+
+	text, err := renderTextTemplate(data)
+	if err != nil {
+		t.Fatalf("renderTextTemplate() failed: %v", err)
+	}
+	for _, want := range []string{"Weekly Report\n", "Past Week", "social\n------", "- Fixed it"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q\ngot:\n%s", want, text)
+		}
+	}
+}
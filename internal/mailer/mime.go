@@ -0,0 +1,193 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// renderedEmail holds both bodies of a report email.
+type renderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// renderEmail renders the report for one recipient, greeting them by name.
+func renderEmail(subject, markdownContent string, period Period, rcpt Recipient) (renderedEmail, error) {
+	data := NewEmailData(subject, markdownContent, period)
+	data.Greeting = greeting(rcpt.Name)
+
+	html, err := renderEmailTemplate(data)
+	if err != nil {
+		return renderedEmail{}, fmt.Errorf("failed to render email template: %w", err)
+	}
+	text, err := renderTextTemplate(data)
+	if err != nil {
+		return renderedEmail{}, fmt.Errorf("failed to render text template: %w", err)
+	}
+	return renderedEmail{Subject: data.Subject, HTML: html, Text: text}, nil
+}
+
+// renderMIMEMessage renders the copy of the report email meant for rcpt and
+// wraps it in a complete MIME message ready to hand to a relay or write to
+// disk. The headers list all To and CC recipients.
+func renderMIMEMessage(from mail.Address, recipients Recipients, rcpt Recipient, messageID, subject, markdownContent string, period Period, attachments []Attachment) ([]byte, error) {
+	email, err := renderEmail(subject, markdownContent, period, rcpt)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := buildMIMEMessage(from, recipients, messageID, email, attachments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build message: %w", err)
+	}
+	return msg, nil
+}
+
+// buildMIMEMessage assembles an RFC 5322 message whose body is a
+// multipart/alternative of the text and HTML versions. With attachments the
+// alternative part is nested in a multipart/mixed envelope.
+func buildMIMEMessage(from mail.Address, recipients Recipients, messageID string, email renderedEmail, attachments []Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	writeHeader := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	writeHeader("From", from.String())
+	if len(recipients.To) > 0 {
+		writeHeader("To", formatAddressList(recipients.To))
+	} else {
+		writeHeader("To", "undisclosed-recipients:;")
+	}
+	if len(recipients.CC) > 0 {
+		writeHeader("Cc", formatAddressList(recipients.CC))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("Message-ID", messageID)
+	writeHeader("MIME-Version", "1.0")
+
+	if len(attachments) == 0 {
+		alternative := multipart.NewWriter(&buf)
+		writeHeader("Content-Type", `multipart/alternative; boundary="`+alternative.Boundary()+`"`)
+		buf.WriteString("\r\n")
+		if err := writeAlternative(alternative, email); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	writeHeader("Content-Type", `multipart/mixed; boundary="`+mixed.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	var altBuf bytes.Buffer
+	alternative := multipart.NewWriter(&altBuf)
+	if err := writeAlternative(alternative, email); err != nil {
+		return nil, err
+	}
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {`multipart/alternative; boundary="` + alternative.Boundary() + `"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(altBuf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, a := range attachments {
+		if err := writeAttachment(mixed, a); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatAddressList(recipients []Recipient) string {
+	addrs := make([]string, len(recipients))
+	for i, r := range recipients {
+		addrs[i] = (&mail.Address{Name: r.Name, Address: r.Email}).String()
+	}
+	return strings.Join(addrs, ", ")
+}
+
+func writeAlternative(w *multipart.Writer, email renderedEmail) error {
+	// the preferred representation goes last
+	if err := writeQuotedPrintablePart(w, `text/plain; charset="utf-8"`, email.Text); err != nil {
+		return err
+	}
+	if err := writeQuotedPrintablePart(w, `text/html; charset="utf-8"`, email.HTML); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func writeQuotedPrintablePart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(w *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+	})
+	if err != nil {
+		return err
+	}
+	return writeBase64Lines(part, a.Data)
+}
+
+// writeBase64Lines writes data base64 encoded in 76 character lines as
+// required by RFC 2045.
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		domain = from[i+1:]
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const ProviderOutbox = "outbox"
+
+var _ Client = (*OutboxMailer)(nil)
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// OutboxMailer writes every message as a complete .eml file into a
+// directory instead of delivering it. It is meant for dry runs, previews and
+// tests; the files open in any mail client.
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+func NewOutboxMailer(dir, from string) *OutboxMailer {
+	if from == "" {
+		from = FromEmail
+	}
+	return &OutboxMailer{
+		dir:  dir,
+		from: from,
+	}
+}
+
+// Send writes one file per recipient, each holding that recipient's
+// personalised copy.
+func (m *OutboxMailer) Send(recipients Recipients, subject, markdownContent string, period Period, isSandbox bool, attachments ...Attachment) ([]SendResult, error) {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox: %w", err)
+	}
+
+	from := mail.Address{Name: FromName, Address: m.from}
+	return sendEach(recipients, func(rcpt Recipient) (SendResult, error) {
+		messageID := newMessageID(from.Address)
+		msg, err := renderMIMEMessage(from, recipients, rcpt, messageID, subject, markdownContent, period, attachments)
+		if err != nil {
+			return SendResult{}, err
+		}
+
+		name := fmt.Sprintf("%s-%s.eml",
+			time.Now().Format("20060102-150405.000000000"),
+			unsafeFileChars.ReplaceAllString(rcpt.Email, "_"))
+		path := filepath.Join(m.dir, name)
+		if err := os.WriteFile(path, msg, 0o644); err != nil {
+			return SendResult{}, fmt.Errorf("failed to write message: %w", err)
+		}
+
+		return SendResult{
+			Provider:  ProviderOutbox,
+			MessageID: messageID,
+			Status:    "written to " + path,
+			Attempts:  1,
+		}, nil
+	})
+}
@@ -0,0 +1,136 @@
+package mailer
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboxMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "outbox")
+	m := NewOutboxMailer(dir, "")
+
+	results, err := m.Send(singleRecipient("Jane Doe", "jane+reports@example.com"), "Weekly Report", "## social\n\n### 8ae1b21 - Fix bug\n", Period{Range: 7 * 24 * time.Hour}, false)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+	if result.Provider != ProviderOutbox || result.Attempts != 1 || result.MessageID == "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read outbox: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 file in outbox, got %d", len(entries))
+	}
+	name := entries[0].Name()
+	if !strings.HasSuffix(name, "-jane_reports_example.com.eml") {
+		t.Errorf("unexpected file name %q", name)
+	}
+	if !strings.HasSuffix(result.Status, name) {
+		t.Errorf("Status %q should point at the written file", result.Status)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("outbox file is not a valid message: %v", err)
+	}
+	if got := msg.Header.Get("Message-ID"); got != result.MessageID {
+		t.Errorf("Message-ID = %q, want %q", got, result.MessageID)
+	}
+	if got := msg.Header.Get("From"); !strings.Contains(got, FromEmail) {
+		t.Errorf("From = %q", got)
+	}
+	if body := decodeParts(t, string(raw))["text/html"]; !strings.Contains(body, "8ae1b21") {
+		t.Error("body should contain the rendered report")
+	}
+}
+
+func TestOutboxMailer_Attachments(t *testing.T) {
+	dir := t.TempDir()
+	m := NewOutboxMailer(dir, "")
+
+	markdown := "## social\n\n### 8ae1b21 - **Fix** bug\n- See [docs](https://example.com)\n"
+	commits := []byte(`[{"hash":"8ae1b21"}]`)
+	_, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Weekly Report", markdown, Period{Range: 7 * 24 * time.Hour}, false,
+		Attachment{Filename: "report.md", ContentType: "text/markdown; charset=utf-8", Data: []byte(markdown)},
+		Attachment{Filename: "commits.json", ContentType: "application/json", Data: commits},
+	)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+
+	msg, _ := mail.ReadMessage(strings.NewReader(string(raw)))
+	if ct := msg.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/mixed") {
+		t.Errorf("Content-Type = %q, want multipart/mixed", ct)
+	}
+
+	parts := decodeParts(t, string(raw))
+	if parts["report.md"] != markdown {
+		t.Errorf("report.md attachment = %q", parts["report.md"])
+	}
+	if parts["commits.json"] != string(commits) {
+		t.Errorf("commits.json attachment = %q", parts["commits.json"])
+	}
+	if !strings.Contains(parts["text/plain"], "8ae1b21 - Fix bug") {
+		t.Errorf("text part should contain the plain report, got:\n%s", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "<h3") {
+		t.Error("html part should contain the rendered report")
+	}
+}
+
+func TestOutboxMailer_OneFilePerRecipient(t *testing.T) {
+	dir := t.TempDir()
+	m := NewOutboxMailer(dir, "")
+
+	recipients := Recipients{
+		To:  []Recipient{{Name: "Jane", Email: "jane@example.com"}},
+		BCC: []Recipient{{Email: "archive@example.com"}},
+	}
+	results, err := m.Send(recipients, "Weekly Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Recipient != "jane@example.com" || results[1].Recipient != "archive@example.com" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	for _, result := range results {
+		path := strings.TrimPrefix(result.Status, "written to ")
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read message for %s: %v", result.Recipient, err)
+		}
+		if strings.Contains(string(raw), "archive@example.com") {
+			t.Errorf("BCC address leaked into the copy for %s", result.Recipient)
+		}
+		text := decodeParts(t, string(raw))["text/plain"]
+		want := "Hi,"
+		if result.Recipient == "jane@example.com" {
+			want = "Hi Jane,"
+		}
+		if !strings.Contains(text, want) {
+			t.Errorf("copy for %s should greet with %q", result.Recipient, want)
+		}
+	}
+}
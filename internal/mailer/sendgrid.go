@@ -1,41 +1,91 @@
 package mailer
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/sendgrid/rest"
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
 	"github.com/youssefM1999/report/pkg/retry"
 )
 
+const ProviderSendGrid = "sendgrid"
+
+// sendGridTimeout bounds the delivery to one recipient, retries and their
+// delays included.
+const sendGridTimeout = 2 * time.Minute
+
+var _ Client = (*SendGridMailer)(nil)
+
 type SendGridMailer struct {
 	from   string
 	apiKey string
 	client *sendgrid.Client
+	policy retry.Policy
 }
 
 func NewSendGridMailer(from, apiKey string) *SendGridMailer {
-	client := sendgrid.NewSendClient(apiKey)
+	return newSendGridMailer(from, apiKey, "")
+}
+
+// newSendGridMailer talks to the v3 API at host; "" is the public API.
+func newSendGridMailer(from, apiKey, host string) *SendGridMailer {
+	request := sendgrid.GetRequest(apiKey, "/v3/mail/send", host)
+	request.Method = http.MethodPost
+	client := &sendgrid.Client{Request: request}
+
+	policy := retry.DefaultPolicy()
+	policy.MaxAttempts = MaxRetries
+	policy.Retryable = func(err error) bool {
+		var sgErr *SendGridError
+		if errors.As(err, &sgErr) {
+			return sgErr.Temporary()
+		}
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
 	return &SendGridMailer{
 		from:   from,
 		apiKey: apiKey,
 		client: client,
+		policy: policy,
 	}
 }
 
-func (m *SendGridMailer) Send(email, username, subject, markdownContent string, period time.Duration, isSandbox bool) (int, error) {
-	from := mail.NewEmail(FromName, m.from)
-	to := mail.NewEmail(username, email)
+// Send makes one API call per recipient so that every recipient gets a
+// personalised copy and a rejected address does not stop delivery to the
+// others; failures are joined as RecipientErrors. SendGrid reserves the To
+// and Cc headers and derives them from the personalisation, so each copy,
+// BCC ones included, is addressed to its recipient only.
+func (m *SendGridMailer) Send(recipients Recipients, subject, markdownContent string, period Period, isSandbox bool, attachments ...Attachment) ([]SendResult, error) {
+	return sendEach(recipients, func(rcpt Recipient) (SendResult, error) {
+		return m.sendOne(rcpt, subject, markdownContent, period, isSandbox, attachments)
+	})
+}
 
-	data := NewEmailData(subject, markdownContent, period)
+func (m *SendGridMailer) sendOne(rcpt Recipient, subject, markdownContent string, period Period, isSandbox bool, attachments []Attachment) (SendResult, error) {
+	from := mail.NewEmail(FromName, m.from)
+	to := mail.NewEmail(rcpt.Name, rcpt.Email)
 
-	body, err := renderEmailTemplate(data)
+	rendered, err := renderEmail(subject, markdownContent, period, rcpt)
 	if err != nil {
-		return -1, fmt.Errorf("failed to render email template: %w", err)
+		return SendResult{}, err
 	}
 
-	message := mail.NewSingleEmail(from, data.Subject, to, "", body)
+	message := mail.NewSingleEmail(from, rendered.Subject, to, rendered.Text, rendered.HTML)
+	for _, a := range attachments {
+		message.AddAttachment(mail.NewAttachment().
+			SetContent(base64.StdEncoding.EncodeToString(a.Data)).
+			SetType(a.ContentType).
+			SetFilename(a.Filename).
+			SetDisposition("attachment"))
+	}
 
 	message.SetMailSettings(&mail.MailSettings{
 		SandboxMode: &mail.Setting{
@@ -43,15 +93,117 @@ func (m *SendGridMailer) Send(email, username, subject, markdownContent string,
 		},
 	})
 
-	var statusCode int
-	err = retry.Retry(func() error {
-		response, err := m.client.Send(message)
-		statusCode = response.StatusCode
-		return err
-	}, MaxRetries)
+	result := SendResult{Provider: ProviderSendGrid}
+	var response *rest.Response
+	ctx, cancel := context.WithTimeout(context.Background(), sendGridTimeout)
+	defer cancel()
+	err = retry.Do(ctx, m.policy, func(ctx context.Context) error {
+		result.Attempts++
+		var err error
+		response, err = m.client.SendWithContext(ctx, message)
+		if err != nil {
+			result.Status = "request failed"
+			return err
+		}
+		result.Status = fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode))
+		return newSendGridError(response)
+	})
 	if err != nil {
-		return -1, fmt.Errorf("failed to send email: %w after %d retries", err, MaxRetries)
+		return result, fmt.Errorf("failed to send email: %w", err)
 	}
 
-	return statusCode, nil
+	if ids := response.Headers["X-Message-Id"]; len(ids) > 0 {
+		result.MessageID = ids[0]
+	}
+	return result, nil
+}
+
+// Typed SendGrid failures; match them with errors.Is on the error returned
+// by Send.
+var (
+	ErrSendGridAuth        = errors.New("sendgrid rejected the API key")
+	ErrSendGridRateLimited = errors.New("sendgrid rate limit exceeded")
+	ErrSendGridBadRequest  = errors.New("sendgrid rejected the request")
+	ErrSendGridServer      = errors.New("sendgrid server error")
+)
+
+// SendGridError is a non-2xx response from the v3 mail API.
+type SendGridError struct {
+	StatusCode int
+	Body       string // raw response body, usually {"errors":[...]}
+	Kind       error  // one of the ErrSendGrid* values
+	retryAfter time.Duration
+}
+
+func (e *SendGridError) Error() string {
+	msg := fmt.Sprintf("%v (%d %s)", e.Kind, e.StatusCode, http.StatusText(e.StatusCode))
+	if details := sendGridErrorMessages(e.Body); details != "" {
+		return msg + ": " + details
+	}
+	if e.Body != "" {
+		return msg + ": " + e.Body
+	}
+	return msg
+}
+
+func (e *SendGridError) Unwrap() error {
+	return e.Kind
+}
+
+func (e *SendGridError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// Temporary reports whether repeating the request may succeed.
+func (e *SendGridError) Temporary() bool {
+	return e.Kind == ErrSendGridRateLimited || e.Kind == ErrSendGridServer
+}
+
+// newSendGridError maps a response to a typed error, or nil for 2xx.
+func newSendGridError(response *rest.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return nil
+	}
+
+	var kind error
+	switch code := response.StatusCode; {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		kind = ErrSendGridAuth
+	case code == http.StatusTooManyRequests:
+		kind = ErrSendGridRateLimited
+	case code >= 500:
+		kind = ErrSendGridServer
+	default:
+		kind = ErrSendGridBadRequest
+	}
+
+	return &SendGridError{
+		StatusCode: response.StatusCode,
+		Body:       strings.TrimSpace(response.Body),
+		Kind:       kind,
+		retryAfter: retry.ParseRetryAfter(http.Header(response.Headers).Get("Retry-After"), time.Now()),
+	}
+}
+
+// sendGridErrorMessages extracts the messages from a v3 error body.
+func sendGridErrorMessages(body string) string {
+	var parsed struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		return ""
+	}
+
+	messages := make([]string, 0, len(parsed.Errors))
+	for _, e := range parsed.Errors {
+		if e.Field != "" {
+			messages = append(messages, fmt.Sprintf("%s (%s)", e.Message, e.Field))
+			continue
+		}
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
 }
@@ -0,0 +1,253 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSendGrid is an httptest server speaking the v3 mail send API. Each
+// request is answered with the next scripted response; the last one repeats.
+type fakeSendGrid struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	responses []fakeResponse
+	requests  []sendGridRequest
+}
+
+type fakeResponse struct {
+	status  int
+	body    string
+	headers map[string]string
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type sendGridRequest struct {
+	Personalizations []struct {
+		To  []sendGridAddress `json:"to"`
+		CC  []sendGridAddress `json:"cc"`
+		BCC []sendGridAddress `json:"bcc"`
+	} `json:"personalizations"`
+	Subject string `json:"subject"`
+	Content []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"content"`
+	authorization string
+}
+
+func newFakeSendGrid(t *testing.T, responses ...fakeResponse) *fakeSendGrid {
+	t.Helper()
+	f := &fakeSendGrid{responses: responses}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v3/mail/send" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req sendGridRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		req.authorization = r.Header.Get("Authorization")
+
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		resp := f.responses[0]
+		if len(f.responses) > 1 {
+			f.responses = f.responses[1:]
+		}
+		f.mu.Unlock()
+
+		for k, v := range resp.headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.status)
+		w.Write([]byte(resp.body))
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeSendGrid) received() []sendGridRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sendGridRequest(nil), f.requests...)
+}
+
+func newTestSendGridMailer(f *fakeSendGrid) *SendGridMailer {
+	m := newSendGridMailer("reports@example.com", "test-key", f.server.URL)
+	m.policy.BaseDelay = time.Millisecond
+	return m
+}
+
+var accepted = fakeResponse{status: http.StatusAccepted, headers: map[string]string{"X-Message-Id": "sg-123"}}
+
+func TestSendGridMailer_Send(t *testing.T) {
+	f := newFakeSendGrid(t, accepted)
+	m := newTestSendGridMailer(f)
+
+	results, err := m.Send(singleRecipient("Jane Doe", "jane@example.com"), "Weekly Report", "## social\n", Period{Range: 7 * 24 * time.Hour}, false)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if got := results[0]; got.MessageID != "sg-123" || got.Attempts != 1 || got.Status != "202 Accepted" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	req := f.received()[0]
+	if req.authorization != "Bearer test-key" {
+		t.Errorf("Authorization = %q", req.authorization)
+	}
+	if req.Subject != "Weekly Report" || len(req.Personalizations) != 1 || req.Personalizations[0].To[0].Email != "jane@example.com" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if len(req.Content) != 2 || req.Content[0].Type != "text/plain" || !strings.Contains(req.Content[1].Value, "Hi Jane Doe,") {
+		t.Errorf("request should carry the personalised text and html parts: %+v", req.Content)
+	}
+}
+
+func TestSendGridMailer_SendsEachRecipientTheirOwnCopy(t *testing.T) {
+	rejected := fakeResponse{status: http.StatusBadRequest, body: `{"errors":[{"message":"Does not contain a valid address."}]}`}
+	f := newFakeSendGrid(t, accepted, rejected, accepted)
+	m := newTestSendGridMailer(f)
+
+	recipients := Recipients{
+		CC:  []Recipient{{Name: "Jane Doe", Email: "jane@example.com"}, {Name: "Broken", Email: "broken@example"}},
+		BCC: []Recipient{{Email: "archive@example.com"}, {Email: "JANE@example.com"}},
+	}
+	results, err := m.Send(recipients, "Weekly Report", "## social\n", Period{Range: 7 * 24 * time.Hour}, false)
+
+	var rcptErr *RecipientError
+	if !errors.As(err, &rcptErr) || rcptErr.Recipient != "broken@example" || !errors.Is(err, ErrSendGridBadRequest) {
+		t.Fatalf("expected a RecipientError for the rejected address, got: %v", err)
+	}
+	if len(results) != 2 || results[0].Recipient != "jane@example.com" || results[1].Recipient != "archive@example.com" {
+		t.Fatalf("the other recipients should still be reached, got %+v", results)
+	}
+
+	// the duplicate address is only sent to once
+	received := f.received()
+	if len(received) != 3 {
+		t.Fatalf("expected one request per recipient, got %d", len(received))
+	}
+	for i, want := range []string{"jane@example.com", "broken@example", "archive@example.com"} {
+		p := received[i].Personalizations
+		if len(p) != 1 || len(p[0].To) != 1 || p[0].To[0].Email != want || len(p[0].CC) != 0 || len(p[0].BCC) != 0 {
+			t.Errorf("request %d should be addressed to %s only: %+v", i, want, p)
+		}
+	}
+	if !strings.Contains(received[0].Content[1].Value, "Hi Jane Doe,") || !strings.Contains(received[2].Content[1].Value, "Hi,") {
+		t.Errorf("every copy should greet its own recipient")
+	}
+}
+
+func TestSendGridMailer_StatusErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakeResponse
+		kind     error
+		attempts int
+		message  string
+	}{
+		{
+			name:     "unauthorized",
+			response: fakeResponse{status: http.StatusUnauthorized, body: `{"errors":[{"message":"The provided authorization grant is invalid, expired, or revoked","field":null,"help":null}]}`},
+			kind:     ErrSendGridAuth,
+			attempts: 1,
+			message:  "authorization grant is invalid",
+		},
+		{
+			name:     "bad request",
+			response: fakeResponse{status: http.StatusBadRequest, body: `{"errors":[{"message":"Does not contain a valid address.","field":"personalizations.0.to.0.email"}]}`},
+			kind:     ErrSendGridBadRequest,
+			attempts: 1,
+			message:  "Does not contain a valid address. (personalizations.0.to.0.email)",
+		},
+		{
+			name:     "rate limited",
+			response: fakeResponse{status: http.StatusTooManyRequests, body: `{"errors":[{"message":"too many requests"}]}`, headers: map[string]string{"Retry-After": "0"}},
+			kind:     ErrSendGridRateLimited,
+			attempts: MaxRetries,
+			message:  "too many requests",
+		},
+		{
+			name:     "server error",
+			response: fakeResponse{status: http.StatusBadGateway, body: "upstream unavailable"},
+			kind:     ErrSendGridServer,
+			attempts: MaxRetries,
+			message:  "upstream unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeSendGrid(t, tt.response)
+			m := newTestSendGridMailer(f)
+
+			results, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+			if err == nil {
+				t.Fatalf("Send() should fail on %d", tt.response.status)
+			}
+			if len(results) != 0 {
+				t.Errorf("no result expected for a failed delivery, got %+v", results)
+			}
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("error should match %v, got: %v", tt.kind, err)
+			}
+			var sgErr *SendGridError
+			if !errors.As(err, &sgErr) || sgErr.StatusCode != tt.response.status {
+				t.Errorf("error should be a *SendGridError with status %d, got: %v", tt.response.status, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error should surface the SendGrid message %q, got: %v", tt.message, err)
+			}
+			if got := len(f.received()); got != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, got)
+			}
+		})
+	}
+}
+
+func TestSendGridMailer_RetriesTransientThenSucceeds(t *testing.T) {
+	f := newFakeSendGrid(t,
+		fakeResponse{status: http.StatusServiceUnavailable},
+		fakeResponse{status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "0"}},
+		accepted,
+	)
+	m := newTestSendGridMailer(f)
+
+	results, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if results[0].Attempts != 3 || results[0].MessageID != "sg-123" {
+		t.Errorf("unexpected result: %+v", results[0])
+	}
+}
+
+func TestSendGridMailer_NetworkError(t *testing.T) {
+	f := newFakeSendGrid(t, accepted)
+	m := newTestSendGridMailer(f)
+	f.server.Close()
+
+	// used to panic on the nil response
+	results, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+	if err == nil {
+		t.Fatal("Send() should fail when the API is unreachable")
+	}
+	if len(results) != 0 {
+		t.Errorf("no result expected for a failed delivery, got %+v", results)
+	}
+}
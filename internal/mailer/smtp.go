@@ -0,0 +1,200 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SMTPSecurityStartTLS = "starttls"
+	SMTPSecurityTLS      = "tls"
+	SMTPSecurityNone     = "none"
+
+	SMTPAuthPlain = "plain"
+	SMTPAuthLogin = "login"
+	SMTPAuthNone  = "none"
+)
+
+const (
+	ProviderSMTP = "smtp"
+	smtpTimeout  = 30 * time.Second
+)
+
+var _ Client = (*SMTPMailer)(nil)
+
+// SMTPConfig describes how to reach an SMTP relay.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Security string // starttls (default), tls or none
+	Auth     string // plain (default when a username is set), login or none
+}
+
+// SMTPMailer delivers the rendered report through an SMTP relay.
+type SMTPMailer struct {
+	cfg       SMTPConfig
+	tlsConfig *tls.Config
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	if cfg.Security == "" {
+		cfg.Security = SMTPSecurityStartTLS
+	}
+	if cfg.Auth == "" {
+		cfg.Auth = SMTPAuthPlain
+		if cfg.Username == "" {
+			cfg.Auth = SMTPAuthNone
+		}
+	}
+	if cfg.From == "" {
+		cfg.From = FromEmail
+	}
+	return &SMTPMailer{
+		cfg:       cfg,
+		tlsConfig: &tls.Config{ServerName: cfg.Host},
+	}
+}
+
+// Send renders a personalised copy of the report for every recipient and
+// hands each to the relay separately. In sandbox mode the messages are
+// rendered but never handed to the relay.
+func (m *SMTPMailer) Send(recipients Recipients, subject, markdownContent string, period Period, isSandbox bool, attachments ...Attachment) ([]SendResult, error) {
+	from := mail.Address{Name: FromName, Address: m.cfg.From}
+
+	return sendEach(recipients, func(rcpt Recipient) (SendResult, error) {
+		messageID := newMessageID(from.Address)
+		msg, err := renderMIMEMessage(from, recipients, rcpt, messageID, subject, markdownContent, period, attachments)
+		if err != nil {
+			return SendResult{}, err
+		}
+
+		result := SendResult{Provider: ProviderSMTP, MessageID: messageID}
+		if isSandbox {
+			result.Status = "sandbox"
+			return result, nil
+		}
+
+		result.Attempts = 1
+		if err := m.deliver(from.Address, []string{rcpt.Email}, msg); err != nil {
+			return result, fmt.Errorf("failed to send email: %w", err)
+		}
+		result.Status = "queued"
+		return result, nil
+	})
+}
+
+func (m *SMTPMailer) deliver(from string, to []string, msg []byte) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	switch m.cfg.Security {
+	case SMTPSecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, m.tlsConfig)
+	case SMTPSecurityStartTLS, SMTPSecurityNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return fmt.Errorf("unknown SMTP security mode %q", m.cfg.Security)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.cfg.Security == SMTPSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(m.tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if auth, err := m.auth(); err != nil {
+		return err
+	} else if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("rcpt %s: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *SMTPMailer) auth() (smtp.Auth, error) {
+	switch m.cfg.Auth {
+	case SMTPAuthNone:
+		return nil, nil
+	case SMTPAuthPlain:
+		return smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host), nil
+	case SMTPAuthLogin:
+		return &loginAuth{username: m.cfg.Username, password: m.cfg.Password}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMTP auth mechanism %q", m.cfg.Auth)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide
+// but which many corporate relays still require.
+type loginAuth struct {
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, errors.New("refusing LOGIN auth over an unencrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+	}
+}
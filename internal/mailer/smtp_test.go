@@ -0,0 +1,438 @@
+package mailer
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal in-process SMTP server that accepts a single
+// message per connection and records what it received.
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	implicit  bool
+
+	mu       sync.Mutex
+	messages []receivedMessage
+	authLog  []string
+}
+
+type receivedMessage struct {
+	from string
+	to   []string
+	data string
+	tls  bool
+}
+
+func newFakeSMTPServer(t *testing.T, implicitTLS bool) *fakeSMTPServer {
+	t.Helper()
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t)}}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	if implicitTLS {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	s := &fakeSMTPServer{listener: listener, tlsConfig: tlsConfig, implicit: implicitTLS}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) received() []receivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	isTLS := s.implicit
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	var msg receivedMessage
+	reply("220 fake.example.com ESMTP")
+	for {
+		line, err := readLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-fake.example.com")
+			if !isTLS {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case "STARTTLS":
+			reply("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, isTLS = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			var creds string
+			switch strings.ToUpper(mech) {
+			case "PLAIN":
+				decoded, _ := base64.StdEncoding.DecodeString(initial)
+				creds = "PLAIN " + strings.ReplaceAll(string(decoded), "\x00", ":")
+			case "LOGIN":
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				u, _ := readLine()
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				p, _ := readLine()
+				user, _ := base64.StdEncoding.DecodeString(u)
+				pass, _ := base64.StdEncoding.DecodeString(p)
+				creds = "LOGIN " + string(user) + ":" + string(pass)
+			}
+			s.mu.Lock()
+			s.authLog = append(s.authLog, creds)
+			s.mu.Unlock()
+			if strings.HasSuffix(creds, ":wrong") {
+				reply("535 authentication failed")
+				continue
+			}
+			reply("235 authenticated")
+		case "MAIL":
+			msg = receivedMessage{from: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>"), tls: isTLS}
+			reply("250 ok")
+		case "RCPT":
+			rcpt := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if strings.HasPrefix(rcpt, "bounce") {
+				reply("550 no such user")
+				continue
+			}
+			msg.to = append(msg.to, rcpt)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := readLine()
+				if err != nil {
+					return
+				}
+				if l == "." {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, ".") + "\r\n")
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func singleRecipient(name, email string) Recipients {
+	return Recipients{To: []Recipient{{Name: name, Email: email}}}
+}
+
+func newTestSMTPMailer(server *fakeSMTPServer, cfg SMTPConfig) *SMTPMailer {
+	cfg.Host = "127.0.0.1"
+	cfg.Port = server.port()
+	m := NewSMTPMailer(cfg)
+	m.tlsConfig.InsecureSkipVerify = true
+	return m
+}
+
+// decodeParts parses a MIME message and returns every leaf part keyed by
+// its media type, or by filename for attachments, with transfer encodings
+// undone.
+func decodeParts(t *testing.T, data string) map[string]string {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	parts := map[string]string{}
+	collectParts(t, textproto.MIMEHeader(msg.Header), msg.Body, parts)
+	return parts
+}
+
+func collectParts(t *testing.T, header textproto.MIMEHeader, body io.Reader, parts map[string]string) {
+	t.Helper()
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("invalid Content-Type %q: %v", header.Get("Content-Type"), err)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := r.NextRawPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to read part: %v", err)
+			}
+			collectParts(t, part.Header, part, parts)
+		}
+	}
+
+	switch header.Get("Content-Transfer-Encoding") {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	decoded, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("failed to decode %s part: %v", mediaType, err)
+	}
+
+	key := mediaType
+	if _, disposition, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && disposition["filename"] != "" {
+		key = disposition["filename"]
+	}
+	parts[key] = string(decoded)
+}
+
+func TestSMTPMailer_StartTLSPlainAuth(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	m := newTestSMTPMailer(server, SMTPConfig{
+		Username: "reporter",
+		Password: "secret",
+		From:     "reports@example.com",
+	})
+
+	results, err := m.Send(singleRecipient("Jane Doe", "jane@example.com"), "Weekly Report", "## social\n\n### 8ae1b21 - Fix bug\n- Fixed it\n", Period{Range: 7 * 24 * time.Hour}, false)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	result := results[0]
+
+	if result.Recipient != "jane@example.com" || result.Provider != ProviderSMTP || result.Attempts != 1 || result.Status != "queued" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	messages := server.received()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	got := messages[0]
+	if !got.tls {
+		t.Error("message should have been sent after STARTTLS")
+	}
+	if got.from != "reports@example.com" || len(got.to) != 1 || got.to[0] != "jane@example.com" {
+		t.Errorf("envelope = %s -> %v", got.from, got.to)
+	}
+	if server.authLog[0] != "PLAIN :reporter:secret" {
+		t.Errorf("auth = %q", server.authLog[0])
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Weekly Report" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if id := msg.Header.Get("Message-ID"); id != result.MessageID {
+		t.Errorf("Message-ID = %q, want %q", id, result.MessageID)
+	}
+	if to := msg.Header.Get("To"); !strings.Contains(to, "Jane Doe") {
+		t.Errorf("To = %q", to)
+	}
+	parts := decodeParts(t, got.data)
+	for _, mediaType := range []string{"text/html", "text/plain"} {
+		for _, want := range []string{"Weekly Report", "Hi Jane Doe,", "8ae1b21", "Past Week"} {
+			if !strings.Contains(parts[mediaType], want) {
+				t.Errorf("%s part should contain %q", mediaType, want)
+			}
+		}
+	}
+}
+
+func TestSMTPMailer_ImplicitTLSLoginAuth(t *testing.T) {
+	server := newFakeSMTPServer(t, true)
+	m := newTestSMTPMailer(server, SMTPConfig{
+		Username: "reporter",
+		Password: "secret",
+		Security: SMTPSecurityTLS,
+		Auth:     SMTPAuthLogin,
+	})
+
+	if _, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false); err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(server.received()) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(server.received()))
+	}
+	if server.authLog[0] != "LOGIN reporter:secret" {
+		t.Errorf("auth = %q", server.authLog[0])
+	}
+	if from := server.received()[0].from; from != FromEmail {
+		t.Errorf("from = %q, want default %q", from, FromEmail)
+	}
+}
+
+func TestSMTPMailer_AuthFailure(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	m := newTestSMTPMailer(server, SMTPConfig{Username: "reporter", Password: "wrong"})
+
+	_, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+	if err == nil || !strings.Contains(err.Error(), "535") {
+		t.Errorf("Send() should surface the auth failure, got: %v", err)
+	}
+	if len(server.received()) != 0 {
+		t.Error("no message should be delivered after failed auth")
+	}
+}
+
+func TestSMTPMailer_Sandbox(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	m := newTestSMTPMailer(server, SMTPConfig{})
+
+	results, err := m.Send(singleRecipient("Jane", "jane@example.com"), "Report", "hello", Period{Range: 7 * 24 * time.Hour}, true)
+	if err != nil {
+		t.Fatalf("Send() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Status != "sandbox" || results[0].Attempts != 0 {
+		t.Errorf("unexpected sandbox results: %+v", results)
+	}
+	if len(server.received()) != 0 {
+		t.Error("sandbox mode should not deliver the message")
+	}
+}
+
+func TestSMTPMailer_MultipleRecipients(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	m := newTestSMTPMailer(server, SMTPConfig{})
+
+	recipients := Recipients{
+		To:  []Recipient{{Name: "Jane Doe", Email: "jane@example.com"}, {Email: "bounce@example.com"}},
+		CC:  []Recipient{{Name: "Lead", Email: "lead@example.com"}},
+		BCC: []Recipient{{Name: "Archive", Email: "archive@example.com"}, {Email: "JANE@example.com"}},
+	}
+	results, err := m.Send(recipients, "Report", "hello", Period{Range: 7 * 24 * time.Hour}, false)
+
+	var rcptErr *RecipientError
+	if !errors.As(err, &rcptErr) || rcptErr.Recipient != "bounce@example.com" || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Send() should report the rejected recipient, got: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %+v", results)
+	}
+
+	messages := server.received()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 delivered messages, got %d", len(messages))
+	}
+	greetings := map[string]string{
+		"jane@example.com":    "Hi Jane Doe,",
+		"lead@example.com":    "Hi Lead,",
+		"archive@example.com": "Hi Archive,",
+	}
+	for _, got := range messages {
+		if len(got.to) != 1 {
+			t.Fatalf("each copy should have a single envelope recipient, got %v", got.to)
+		}
+		msg, err := mail.ReadMessage(strings.NewReader(got.data))
+		if err != nil {
+			t.Fatalf("failed to parse message: %v", err)
+		}
+		if to := msg.Header.Get("To"); !strings.Contains(to, "jane@example.com") || !strings.Contains(to, "bounce@example.com") {
+			t.Errorf("To = %q", to)
+		}
+		if cc := msg.Header.Get("Cc"); !strings.Contains(cc, "lead@example.com") {
+			t.Errorf("Cc = %q", cc)
+		}
+		if strings.Contains(got.data, "archive@example.com") {
+			t.Error("BCC recipients must not appear in the message")
+		}
+		if text := decodeParts(t, got.data)["text/plain"]; !strings.Contains(text, greetings[got.to[0]]) {
+			t.Errorf("copy for %s should greet with %q, got:\n%s", got.to[0], greetings[got.to[0]], text)
+		}
+	}
+}
+
+func TestNewSMTPMailer_Defaults(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: 587})
+	if m.cfg.Security != SMTPSecurityStartTLS {
+		t.Errorf("Security = %q, want %q", m.cfg.Security, SMTPSecurityStartTLS)
+	}
+	if m.cfg.Auth != SMTPAuthNone {
+		t.Errorf("Auth without username = %q, want %q", m.cfg.Auth, SMTPAuthNone)
+	}
+	if m.tlsConfig.ServerName != "smtp.example.com" {
+		t.Errorf("ServerName = %q", m.tlsConfig.ServerName)
+	}
+}
@@ -0,0 +1,131 @@
+package period
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ThisWeek   = "this-week"
+	LastWeek   = "last-week"
+	ThisMonth  = "this-month"
+	LastMonth  = "last-month"
+	ThisSprint = "this-sprint"
+	LastSprint = "last-sprint"
+)
+
+// Presets lists the names accepted by Preset.
+var Presets = []string{ThisWeek, LastWeek, ThisMonth, LastMonth, ThisSprint, LastSprint}
+
+const dateLayout = "2006-01-02"
+
+// Window is the time range a report covers: commits made at or after Since
+// and before Until.
+type Window struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Validate checks that the window is not empty.
+func (w Window) Validate() error {
+	if !w.Since.Before(w.Until) {
+		return fmt.Errorf("empty window: %s is not before %s", w.Since.Format(time.RFC3339), w.Until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Sprint describes sprints of a fixed number of days. Anchor is the first
+// day of any sprint, past or future; only its date is used.
+type Sprint struct {
+	Days   int
+	Anchor time.Time
+}
+
+var errNoSprint = errors.New("sprint presets need a sprint length and anchor (SPRINT_DAYS and SPRINT_ANCHOR)")
+
+// Preset returns the window named name as seen at now, with days starting at
+// midnight in now's location. Weeks start on Monday. The windows of the
+// current week, month and sprint end at now; the previous ones end where the
+// current ones start.
+func Preset(name string, now time.Time, sprint Sprint) (Window, error) {
+	today := StartOfDay(now)
+
+	var start time.Time
+	var previous func(time.Time) time.Time
+	switch name {
+	case ThisWeek, LastWeek:
+		start = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		previous = func(t time.Time) time.Time { return t.AddDate(0, 0, -7) }
+	case ThisMonth, LastMonth:
+		start = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		previous = func(t time.Time) time.Time { return t.AddDate(0, -1, 0) }
+	case ThisSprint, LastSprint:
+		if sprint.Days < 1 || sprint.Anchor.IsZero() {
+			return Window{}, errNoSprint
+		}
+		y, m, d := sprint.Anchor.Date()
+		anchor := time.Date(y, m, d, 0, 0, 0, 0, today.Location())
+		sprints := floorDiv(daysBetween(anchor, today), sprint.Days)
+		start = anchor.AddDate(0, 0, sprints*sprint.Days)
+		previous = func(t time.Time) time.Time { return t.AddDate(0, 0, -sprint.Days) }
+	default:
+		return Window{}, fmt.Errorf("unknown period %q (want one of %s)", name, strings.Join(Presets, ", "))
+	}
+
+	if strings.HasPrefix(name, "this-") {
+		return Window{Since: start, Until: now}, nil
+	}
+	return Window{Since: previous(start), Until: start}, nil
+}
+
+// ParseSince parses the start of a window: a date, which starts at
+// midnight in loc, or an RFC 3339 time.
+func ParseSince(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return t, nil
+	}
+	return parseTime(value)
+}
+
+// ParseUntil parses the end of a window: a date, which includes that whole
+// day in loc, or an RFC 3339 time.
+func ParseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return parseTime(value)
+}
+
+func parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t, nil
+}
+
+// StartOfDay returns midnight of t's day in t's location.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts the calendar days from a to b, ignoring daylight
+// saving changes in between.
+func daysBetween(a, b time.Time) int {
+	utc := func(t time.Time) time.Time {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	return int(utc(b).Sub(utc(a)).Hours() / 24)
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
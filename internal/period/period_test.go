@@ -0,0 +1,103 @@
+package period
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreset(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// Wednesday March 13, 2024, 08:30 in Tokyo, still Tuesday in UTC
+	now := time.Date(2024, 3, 13, 8, 30, 0, 0, tokyo)
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, tokyo) }
+	sprint := Sprint{Days: 14, Anchor: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name string
+		want Window
+	}{
+		{ThisWeek, Window{day(3, 11), now}},
+		{LastWeek, Window{day(3, 4), day(3, 11)}},
+		{ThisMonth, Window{day(3, 1), now}},
+		{LastMonth, Window{day(2, 1), day(3, 1)}},
+		// sprints start on Jan 1, Jan 15, ... Feb 26, Mar 11
+		{ThisSprint, Window{day(3, 11), now}},
+		{LastSprint, Window{day(2, 26), day(3, 11)}},
+	}
+	for _, tt := range tests {
+		got, err := Preset(tt.name, now, sprint)
+		if err != nil {
+			t.Fatalf("Preset(%q) failed: %v", tt.name, err)
+		}
+		if !got.Since.Equal(tt.want.Since) || !got.Until.Equal(tt.want.Until) {
+			t.Errorf("Preset(%q) = %v - %v, want %v - %v", tt.name, got.Since, got.Until, tt.want.Since, tt.want.Until)
+		}
+	}
+
+	// an anchor after now counts sprints backwards
+	future := Sprint{Days: 14, Anchor: time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)}
+	if got, _ := Preset(LastSprint, now, future); !got.Since.Equal(day(2, 26)) {
+		t.Errorf("Preset(last-sprint) with a future anchor starts %v", got.Since)
+	}
+
+	if _, err := Preset(LastSprint, now, Sprint{}); err == nil {
+		t.Error("sprint presets should require a sprint configuration")
+	}
+	if _, err := Preset("last-year", now, sprint); err == nil || !strings.Contains(err.Error(), LastWeek) {
+		t.Errorf("an unknown preset should list the valid ones, got: %v", err)
+	}
+}
+
+func TestPreset_DaylightSaving(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	// the clocks moved forward on Sunday March 31, 2024
+	now := time.Date(2024, 4, 3, 12, 0, 0, 0, paris)
+	got, err := Preset(LastWeek, now, Sprint{})
+	if err != nil {
+		t.Fatalf("Preset() failed: %v", err)
+	}
+	if want := time.Date(2024, 3, 25, 0, 0, 0, 0, paris); !got.Since.Equal(want) {
+		t.Errorf("Since = %v, want %v", got.Since, want)
+	}
+	if want := time.Date(2024, 4, 1, 0, 0, 0, 0, paris); !got.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", got.Until, want)
+	}
+	if h := got.Until.Sub(got.Since).Hours(); h != 7*24-1 {
+		t.Errorf("the week should be one hour short, got %vh", h)
+	}
+}
+
+func TestParseSinceUntil(t *testing.T) {
+	loc := time.FixedZone("UTC+9", 9*60*60)
+
+	since, err := ParseSince("2024-03-01", loc)
+	if err != nil || !since.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, loc)) {
+		t.Errorf("ParseSince(date) = %v, %v", since, err)
+	}
+	// a date includes the whole day
+	until, err := ParseUntil("2024-03-31", loc)
+	if err != nil || !until.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, loc)) {
+		t.Errorf("ParseUntil(date) = %v, %v", until, err)
+	}
+	exact, err := ParseUntil("2024-03-31T18:00:00Z", loc)
+	if err != nil || !exact.Equal(time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseUntil(time) = %v, %v", exact, err)
+	}
+	if _, err := ParseSince("last tuesday", loc); err == nil {
+		t.Error("ParseSince() should reject other formats")
+	}
+
+	if err := (Window{Since: until, Until: since}).Validate(); err == nil {
+		t.Error("a window ending before it starts should be invalid")
+	}
+	if err := (Window{Since: since, Until: until}).Validate(); err != nil {
+		t.Errorf("Validate() failed: %v", err)
+	}
+}
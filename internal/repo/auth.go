@@ -0,0 +1,59 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+// resolveAuth turns auth settings into credentials, reading the token from
+// its environment variable or file. Nil settings give no credentials.
+func resolveAuth(cfg *config.AuthConfig) (git.Auth, error) {
+	if cfg == nil {
+		return git.Auth{}, nil
+	}
+	if cfg.TokenEnv != "" && cfg.TokenFile != "" {
+		return git.Auth{}, errors.New("auth: set either token_env or token_file, not both")
+	}
+
+	auth := git.Auth{
+		Username:   cfg.Username,
+		SSHKey:     expandHome(cfg.SSHKey),
+		KnownHosts: expandHome(cfg.KnownHosts),
+	}
+	switch {
+	case cfg.TokenEnv != "":
+		auth.Token = strings.TrimSpace(os.Getenv(cfg.TokenEnv))
+		if auth.Token == "" {
+			return git.Auth{}, fmt.Errorf("auth: environment variable %s is not set", cfg.TokenEnv)
+		}
+	case cfg.TokenFile != "":
+		data, err := os.ReadFile(expandHome(cfg.TokenFile))
+		if err != nil {
+			return git.Auth{}, fmt.Errorf("auth: failed to read token file: %w", err)
+		}
+		auth.Token = strings.TrimSpace(string(data))
+		if auth.Token == "" {
+			return git.Auth{}, fmt.Errorf("auth: token file %s is empty", cfg.TokenFile)
+		}
+	}
+	return auth, nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, rest)
+}
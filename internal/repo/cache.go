@@ -0,0 +1,128 @@
+package repo
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+// PruneCache removes the repository caches under reposConfig.Dir that belong
+// to none of the configured repositories, and returns their paths relative
+// to that directory, sorted. Bare caches are removed, and so are the working
+// trees that earlier versions cloned directly into it, but only when they
+// are clean clones of a configured remote or of one cached next to them.
+// Any other working tree is returned as skipped for the caller to report;
+// other files, and anything holding or held by a configured local path or
+// scan root, are left alone. With dryRun nothing is removed.
+func PruneCache(reposConfig config.ReposConfig, dryRun bool) (removed, skipped []string, err error) {
+	baseDir := reposConfig.Dir
+	// the local repositories the walk may reach, never to be touched
+	var local []string
+	addLocal := func(path string) {
+		if abs := absPath(path); within(abs, absPath(baseDir)) {
+			local = append(local, abs)
+		}
+	}
+	keep := map[string]bool{}
+	remotes := map[string]bool{}
+	for _, r := range reposConfig.TargetRepos {
+		if r.URL != "" {
+			keep[filepath.FromSlash(git.CacheDir(r.URL))] = true
+			remotes[r.URL] = true
+		}
+		if r.Path != "" {
+			addLocal(r.Path)
+		}
+	}
+	for _, scan := range reposConfig.Scan {
+		addLocal(scan.Root)
+	}
+
+	// a working tree is only known to be ours when it is a clean clone of a
+	// remote this tool caches, or has cached before
+	legacyClone := func(dir string) bool {
+		url := git.CleanCloneOrigin(dir)
+		if url == "" {
+			return false
+		}
+		return remotes[url] || git.IsCacheOf(filepath.Join(baseDir, filepath.FromSlash(git.CacheDir(url))), url)
+	}
+
+	var stale []string
+	err = filepath.WalkDir(baseDir, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || dir == baseDir {
+			return nil
+		}
+		rel, err := filepath.Rel(baseDir, dir)
+		if err != nil {
+			return err
+		}
+		abs := absPath(dir)
+		if slices.ContainsFunc(local, func(p string) bool { return within(abs, p) }) {
+			return filepath.SkipDir
+		}
+		if slices.ContainsFunc(local, func(p string) bool { return within(p, abs) }) {
+			// only look for caches next to the local repositories
+			return nil
+		}
+		switch {
+		case git.IsBareRepository(dir):
+			if !keep[rel] {
+				stale = append(stale, rel)
+			}
+		case git.IsGitRepository(dir):
+			if !strings.ContainsRune(rel, filepath.Separator) && legacyClone(dir) {
+				stale = append(stale, rel)
+			} else {
+				skipped = append(skipped, rel)
+			}
+		default:
+			return nil
+		}
+		// the contents of a repository are git's business
+		return filepath.SkipDir
+	})
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(stale)
+	sort.Strings(skipped)
+
+	if dryRun {
+		return stale, skipped, nil
+	}
+	for i, rel := range stale {
+		if err := os.RemoveAll(filepath.Join(baseDir, rel)); err != nil {
+			return stale[:i], skipped, fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+	}
+	return stale, skipped, nil
+}
+
+// absPath resolves path like the local repositories' paths are, falling back
+// to the path itself.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(expandHome(path)); err == nil {
+		return abs
+	}
+	return path
+}
+
+// within reports whether path is dir or lies below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
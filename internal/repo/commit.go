@@ -1,44 +1,173 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/youssefM1999/report/pkg/git"
 )
 
+// Commit is one commit of a repository. Message holds the subject line,
+// Body the rest of the message without its trailers.
 type Commit struct {
-	Hash    string
-	Message string
-	Author  string
-	Date    time.Time
-	Content string
+	Hash     string      `json:"hash"`
+	Message  string      `json:"message"`
+	Body     string      `json:"body,omitempty"`
+	Trailers []Trailer   `json:"trailers,omitempty"`
+	Author   string      `json:"author"`
+	Date     time.Time   `json:"date"`
+	Content  string      `json:"diff,omitempty"`
+	Stats    CommitStats `json:"stats"`
+
+	// CoAuthored is set when the commit was included because the user is
+	// named in a Co-authored-by trailer rather than being its author.
+	CoAuthored bool `json:"co_authored,omitempty"`
+
+	// Branches lists the scanned branches the commit is reachable from, and
+	// OnMainBranch tells whether the repo's main branch is one of them.
+	Branches     []string `json:"branches,omitempty"`
+	OnMainBranch bool     `json:"on_main_branch"`
 }
 
+// Trailer is a "Key: value" line from the last paragraph of a commit
+// message, such as Co-authored-by or Reviewed-by.
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+const coAuthorTrailer = "Co-authored-by"
+
+// CoAuthors returns the values of the commit's Co-authored-by trailers.
+func (c *Commit) CoAuthors() []string {
+	var coAuthors []string
+	for _, t := range c.Trailers {
+		if strings.EqualFold(t.Key, coAuthorTrailer) {
+			coAuthors = append(coAuthors, t.Value)
+		}
+	}
+	return coAuthors
+}
+
+// ParseError reports a log record that could not be parsed.
+type ParseError struct {
+	Record int    // index of the record in the log output
+	Text   string // the offending record, fields separated by NUL
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	text := e.Text
+	if len(text) > maxParseErrorText {
+		text = text[:maxParseErrorText] + "..."
+	}
+	return fmt.Sprintf("log record %d %q: %v", e.Record, text, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// maxParseErrorText keeps errors about records with long messages readable.
+const maxParseErrorText = 200
+
+var commitHash = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// parseToCommits parses the log records produced by a git.Backend. Every
+// record must be well formed; the first one that is not is reported as a
+// *ParseError rather than skipped.
 func parseToCommits(output []byte) ([]*Commit, error) {
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) == 0 || lines[0] == "" {
+	if len(output) == 0 {
 		return nil, nil
 	}
+	fields := strings.Split(string(output), git.LogFieldTerminator)
+	// the last field is terminated too, which leaves an empty string behind
+	terminated := fields[len(fields)-1] == ""
+	if terminated {
+		fields = fields[:len(fields)-1]
+	}
 
-	commits := make([]*Commit, 0, len(lines))
-	for _, line := range lines {
-		parts := strings.Split(line, "|||")
-		if len(parts) != 4 {
-			continue
+	commits := make([]*Commit, 0, len(fields)/git.LogFields)
+	for i := 0; i < len(fields); i += git.LogFields {
+		record := fields[i:min(i+git.LogFields, len(fields))]
+		commit, err := parseRecord(record)
+		if err == nil && !terminated && i+git.LogFields >= len(fields) {
+			err = errors.New("record is not terminated")
 		}
-
-		timestamp, err := strconv.ParseInt(parts[2], 10, 64)
 		if err != nil {
-			return nil, err
+			return nil, &ParseError{Record: i / git.LogFields, Text: strings.Join(record, git.LogFieldTerminator), Err: err}
 		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
 
-		commits = append(commits, &Commit{
-			Hash:    parts[0],
-			Author:  parts[1],
-			Date:    time.Unix(timestamp, 0),
-			Message: parts[3],
-		})
+func parseRecord(fields []string) (*Commit, error) {
+	if len(fields) != git.LogFields {
+		return nil, fmt.Errorf("expected %d fields, got %d", git.LogFields, len(fields))
+	}
+	hash, author, date, message := fields[0], fields[1], fields[2], fields[3]
+	if !commitHash.MatchString(hash) {
+		return nil, fmt.Errorf("invalid commit hash %q", hash)
+	}
+	timestamp, err := strconv.ParseInt(date, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp: %w", err)
 	}
 
-	return commits, nil
+	subject, body, trailers := parseMessage(message)
+	return &Commit{
+		Hash:     hash,
+		Author:   author,
+		Date:     time.Unix(timestamp, 0),
+		Message:  subject,
+		Body:     body,
+		Trailers: trailers,
+	}, nil
+}
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+	trailerLine    = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+)
+
+// parseMessage splits a raw commit message into its subject, body and
+// trailers. Like git, the subject is the first paragraph joined into one
+// line, and trailers are only recognised in the last paragraph when every
+// line of it is a trailer or a continuation of one.
+func parseMessage(message string) (string, string, []Trailer) {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	paragraphs := paragraphBreak.Split(message, -1)
+	subject := strings.Join(strings.Fields(paragraphs[0]), " ")
+	rest := paragraphs[1:]
+
+	var trailers []Trailer
+	if len(rest) > 0 {
+		if parsed, ok := parseTrailers(rest[len(rest)-1]); ok {
+			trailers = parsed
+			rest = rest[:len(rest)-1]
+		}
+	}
+	return subject, strings.TrimSpace(strings.Join(rest, "\n\n")), trailers
+}
+
+func parseTrailers(paragraph string) ([]Trailer, bool) {
+	var trailers []Trailer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(trailers) > 0 {
+			last := &trailers[len(trailers)-1]
+			last.Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		m := trailerLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, false
+		}
+		trailers = append(trailers, Trailer{Key: m[1], Value: strings.TrimSpace(m[2])})
+	}
+	return trailers, len(trailers) > 0
 }
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+// DefaultScanDepth is how many directory levels below a scan root are
+// searched when the scan sets no depth.
+const DefaultScanDepth = 3
+
+// DiscoverRepos finds the git working copies under scan.Root and returns
+// them as local repositories named after their path relative to the root.
+// Hidden directories and the contents of a working copy are not searched.
+func DiscoverRepos(scan config.ScanConfig) ([]config.RepoConfig, error) {
+	root, err := filepath.Abs(expandHome(scan.Root))
+	if err != nil {
+		return nil, err
+	}
+	maxDepth := scan.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultScanDepth
+	}
+
+	var repos []config.RepoConfig
+	err = filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && (strings.HasPrefix(d.Name(), ".") || excluded(scan.Exclude, rel)) {
+			return fs.SkipDir
+		}
+
+		if git.IsGitRepository(dir) {
+			name := rel
+			if rel == "." {
+				name = filepath.Base(root)
+			}
+			repos = append(repos, config.RepoConfig{Name: name, Path: dir})
+			return fs.SkipDir
+		}
+		if rel != "." && strings.Count(rel, "/")+1 >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func excluded(patterns []string, rel string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		ok, _ := path.Match(pattern, rel)
+		return ok
+	})
+}
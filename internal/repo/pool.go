@@ -0,0 +1,93 @@
+package repo
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+const DefaultParallelism = 4
+
+// forEach calls fn for every index in [0, n) using at most parallelism
+// goroutines. Every index is visited even if earlier calls fail; the
+// returned error joins the failures in index order.
+func forEach(n, parallelism int, fn func(i int) error) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > n {
+		parallelism = n
+	}
+
+	errs := make([]error, n)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range parallelism {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// limitedBackend runs at most cap(slots) git operations at a time across
+// every goroutine sharing it, however the work above it is nested.
+type limitedBackend struct {
+	backend git.Backend
+	slots   chan struct{}
+}
+
+func newLimitedBackend(backend git.Backend, parallelism int) *limitedBackend {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &limitedBackend{backend: backend, slots: make(chan struct{}, parallelism)}
+}
+
+func (b *limitedBackend) acquire() func() {
+	b.slots <- struct{}{}
+	return func() { <-b.slots }
+}
+
+func (b *limitedBackend) Sync(repoDir, url, branch string, opts git.SyncOptions, auth git.Auth) error {
+	defer b.acquire()()
+	return b.backend.Sync(repoDir, url, branch, opts, auth)
+}
+
+func (b *limitedBackend) RemoteBranches(repoDir string) ([]string, error) {
+	defer b.acquire()()
+	return b.backend.RemoteBranches(repoDir)
+}
+
+func (b *limitedBackend) GetCommitsByAuthor(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error) {
+	defer b.acquire()()
+	return b.backend.GetCommitsByAuthor(repoDir, rev, authors, since, until)
+}
+
+func (b *limitedBackend) GetCoAuthoredCommits(repoDir, rev string, since, until time.Time) ([]byte, error) {
+	defer b.acquire()()
+	return b.backend.GetCoAuthoredCommits(repoDir, rev, since, until)
+}
+
+func (b *limitedBackend) GetCommitContents(repoDir, hash string) (string, error) {
+	defer b.acquire()()
+	return b.backend.GetCommitContents(repoDir, hash)
+}
+
+func (b *limitedBackend) GetCommitStats(repoDir, hash string) ([]byte, error) {
+	defer b.acquire()()
+	return b.backend.GetCommitStats(repoDir, hash)
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/youssefM1999/report/pkg/git"
+)
+
+func TestForEach_VisitsEveryIndex(t *testing.T) {
+	results := make([]int, 20)
+	err := forEach(len(results), 4, func(i int) error {
+		results[i] = i * i
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEach() failed: %v", err)
+	}
+	for i, got := range results {
+		if got != i*i {
+			t.Errorf("results[%d] = %d, want %d", i, got, i*i)
+		}
+	}
+}
+
+func TestForEach_BoundsParallelism(t *testing.T) {
+	var running, peak atomic.Int32
+	err := forEach(16, 3, func(i int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("forEach() failed: %v", err)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most 3 concurrent calls, got %d", peak.Load())
+	}
+}
+
+func TestForEach_JoinsErrorsWithoutStopping(t *testing.T) {
+	errOdd := errors.New("odd")
+	var calls atomic.Int32
+	err := forEach(10, 2, func(i int) error {
+		calls.Add(1)
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	})
+	if calls.Load() != 10 {
+		t.Errorf("Expected 10 calls, got %d", calls.Load())
+	}
+	if !errors.Is(err, errOdd) {
+		t.Fatalf("Expected joined error to wrap errOdd, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 5 {
+		t.Errorf("Expected 5 joined errors, got %d", n)
+	}
+}
+
+func TestForEach_Empty(t *testing.T) {
+	if err := forEach(0, 4, func(i int) error { return errors.New("unreachable") }); err != nil {
+		t.Errorf("forEach() on empty input should not fail, got: %v", err)
+	}
+}
+
+// countingBackend records how many diffs are read at the same time.
+type countingBackend struct {
+	git.ExecBackend
+	running, peak atomic.Int32
+}
+
+func (b *countingBackend) GetCommitContents(repoDir, hash string) (string, error) {
+	n := b.running.Add(1)
+	for {
+		p := b.peak.Load()
+		if n <= p || b.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	b.running.Add(-1)
+	return "", nil
+}
+
+func TestLimitedBackend_BoundsNestedWork(t *testing.T) {
+	counting := &countingBackend{}
+	backend := newLimitedBackend(counting, 3)
+	// repositories and their commits both fan out, as in a collection run
+	err := forEach(4, 3, func(int) error {
+		return forEach(8, 3, func(int) error {
+			_, err := backend.GetCommitContents("", "")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("forEach() failed: %v", err)
+	}
+	if counting.peak.Load() > 3 {
+		t.Errorf("Expected at most 3 concurrent git operations, got %d", counting.peak.Load())
+	}
+}
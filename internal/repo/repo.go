@@ -1,7 +1,13 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/youssefM1999/report/internal/config"
@@ -9,8 +15,11 @@ import (
 )
 
 type RepoManager struct {
-	baseDir string
-	repos   []*Repo
+	baseDir      string
+	parallelism  int
+	backend      git.Backend
+	historySince time.Time // zero fetches the full history
+	repos        []*Repo
 }
 
 type Repository interface {
@@ -19,33 +28,145 @@ type Repository interface {
 }
 
 type Repo struct {
-	Name    string
-	URL     string
-	Branch  string
-	RepoDir string
-	Commits []*Commit
+	Name        string
+	URL         string
+	Branch      string
+	Branches    []string // globs of extra remote branches to scan
+	RepoDir     string
+	Local       bool // an existing working copy read in place, never cloned or pulled
+	Commits     []*Commit
+	Status      RepoStatus
+	auth        *config.AuthConfig
+	syncOptions git.SyncOptions
+	parallelism int
+	backend     git.Backend
 }
 
-func NewRepoManager(baseDir string) *RepoManager {
+type SyncState string
+
+const (
+	StatePending SyncState = ""
+	StateCloned  SyncState = "cloned" // cache created
+	StatePulled  SyncState = "pulled" // existing cache fetched
+	StateLocal   SyncState = "local"
+	StateFailed  SyncState = "failed"
+)
+
+// RepoStatus records what happened to a repository during the last sync and
+// commit collection. Err is set when State is StateFailed.
+type RepoStatus struct {
+	Name  string
+	URL   string
+	State SyncState
+	Err   error
+}
+
+func (s RepoStatus) Failed() bool {
+	return s.State == StateFailed
+}
+
+// RepoError ties a failure to the repository it happened in so that
+// aggregated errors stay readable.
+type RepoError struct {
+	Repo string
+	Err  error
+}
+
+func (e *RepoError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Repo, e.Err)
+}
+
+func (e *RepoError) Unwrap() error {
+	return e.Err
+}
+
+// NewRepoManager creates a manager that clones into baseDir and runs at most
+// parallelism git operations at a time, shared by every repository and
+// commit it works on. A nil backend uses the git binary.
+func NewRepoManager(baseDir string, parallelism int, backend git.Backend) *RepoManager {
+	if parallelism < 1 {
+		parallelism = DefaultParallelism
+	}
+	if backend == nil {
+		backend = git.ExecBackend{}
+	}
 	return &RepoManager{
-		baseDir: baseDir,
-		repos:   []*Repo{},
+		baseDir:     baseDir,
+		parallelism: parallelism,
+		backend:     newLimitedBackend(backend, parallelism),
+		repos:       []*Repo{},
 	}
 }
 
+// LimitHistory makes CloneAll fetch only the history committed after
+// since, which is all a report starting then needs. Caches synced without
+// it get their full history back.
+func (rm *RepoManager) LimitHistory(since time.Time) {
+	rm.historySince = since
+}
+
+// CloneAll clones or updates every target repository, and adds the local
+// ones found by the scans. A failing repository or scan does not stop the
+// others: it is recorded as failed in Statuses, left out of Repos, and its
+// error is returned joined with the other failures.
 func (rm *RepoManager) CloneAll(reposConfig config.ReposConfig) error {
-	for _, repoConfig := range reposConfig.TargetRepos {
+	targets := reposConfig.TargetRepos
+	var scanErrs []error
+	for _, scan := range reposConfig.Scan {
+		found, err := DiscoverRepos(scan)
+		if err != nil {
+			err = fmt.Errorf("scan: %w", err)
+			failed := NewLocalRepo(scan.Root, "", scan.Root)
+			failed.fail(err)
+			rm.repos = append(rm.repos, failed)
+			scanErrs = append(scanErrs, &RepoError{Repo: scan.Root, Err: err})
+			continue
+		}
+		targets = append(targets, found...)
+	}
+
+	repos := make([]*Repo, 0, len(targets))
+	seen := map[string]bool{}
+	for _, repoConfig := range targets {
 		repo := rm.NewRepoFromConfig(repoConfig)
-		if err := repo.Clone(); err != nil {
-			return err
+		// a working copy both configured and found by a scan is read once
+		if seen[repo.RepoDir] {
+			continue
 		}
-		rm.repos = append(rm.repos, repo)
+		seen[repo.RepoDir] = true
+		repo.syncOptions.Filter = reposConfig.Filter
+		repo.syncOptions.Since = rm.historySince
+		repos = append(repos, repo)
 	}
-	return nil
+	rm.repos = append(rm.repos, repos...)
+
+	err := forEach(len(repos), rm.parallelism, func(i int) error {
+		if err := repos[i].Clone(); err != nil {
+			return &RepoError{Repo: repos[i].Name, Err: fmt.Errorf("clone: %w", err)}
+		}
+		return nil
+	})
+	if len(scanErrs) == 0 {
+		return err
+	}
+	// keep a single flat list of failures
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return errors.Join(append(scanErrs, joined.Unwrap()...)...)
+	}
+	return errors.Join(append(scanErrs, err)...)
 }
 
 func (rm *RepoManager) NewRepoFromConfig(config config.RepoConfig) *Repo {
-	return NewRepo(config.Name, config.URL, config.Branch, filepath.Join(rm.baseDir, config.Name))
+	// keyed by URL, so that the name can neither escape baseDir nor clash
+	repo := NewRepo(config.Name, config.URL, config.Branch, filepath.Join(rm.baseDir, git.CacheDir(config.URL)))
+	if config.Path != "" {
+		repo = NewLocalRepo(config.Name, config.Branch, config.Path)
+	}
+	repo.Branches = config.Branches
+	repo.auth = config.Auth
+	repo.parallelism = rm.parallelism
+	repo.backend = rm.backend
+	return repo
 }
 
 func NewRepo(name, url, branch, repoDir string) *Repo {
@@ -54,49 +175,381 @@ func NewRepo(name, url, branch, repoDir string) *Repo {
 		URL:     url,
 		Branch:  branch,
 		RepoDir: repoDir,
+		Status:  RepoStatus{Name: name, URL: url},
+		backend: git.ExecBackend{},
+	}
+}
+
+// NewLocalRepo reads the working copy at dir in place. An empty name uses
+// the directory name, and an empty branch reads HEAD.
+func NewLocalRepo(name, branch, dir string) *Repo {
+	if abs, err := filepath.Abs(expandHome(dir)); err == nil {
+		dir = abs
+	}
+	if name == "" {
+		name = filepath.Base(dir)
 	}
+	repo := NewRepo(name, "", branch, dir)
+	repo.Local = true
+	repo.Status.URL = dir
+	return repo
 }
 
+// Clone creates the bare cache of the repository, or fetches it if it is
+// already present, and records the outcome in r.Status. A local repository is only checked to be
+// a working copy; it is left exactly as it is.
 func (r *Repo) Clone() error {
-	return git.Clone(r.RepoDir, r.URL, r.Branch)
+	if r.Local {
+		if !git.IsGitRepository(r.RepoDir) {
+			err := fmt.Errorf("not a git working copy: %s", r.RepoDir)
+			r.fail(err)
+			return err
+		}
+		r.Status.State = StateLocal
+		return nil
+	}
+
+	state := StateCloned
+	if git.IsCacheOf(r.RepoDir, r.URL) {
+		state = StatePulled
+	}
+	auth, err := resolveAuth(r.auth)
+	if err != nil {
+		r.fail(err)
+		return err
+	}
+	opts := r.syncOptions
+	opts.AllBranches = len(r.Branches) > 0
+	if err := r.backend.Sync(r.RepoDir, r.URL, r.Branch, opts, auth); err != nil {
+		err = authHint(err)
+		r.fail(err)
+		return err
+	}
+	r.Status.State = state
+	return nil
+}
+
+// authHint points rejected credentials at the setting to fix.
+func authHint(err error) error {
+	if errors.Is(err, git.ErrAuthFailed) {
+		return fmt.Errorf("%w (check the auth settings of this repository)", err)
+	}
+	return err
+}
+
+func (r *Repo) fail(err error) {
+	r.Status.State = StateFailed
+	r.Status.Err = err
 }
 
-func (r *Repo) GetCommitsByAuthor(author config.UserConfig, since time.Time) error {
-	output, err := git.GetCommitsByAuthor(r.RepoDir, author.Email, since)
+// GetCommitsByAuthor collects the commits made or co-authored under any of
+// the author's identities on the main branch and on every remote branch
+// selected by r.Branches, committed at or after since and before until (a
+// zero until has no bound). A commit reachable from several branches is
+// listed once and annotated with all of them. Commit dates are in the
+// author's Location when it is set.
+func (r *Repo) GetCommitsByAuthor(author config.UserConfig, since, until time.Time) error {
+	authors := authorPatterns(author)
+	coAuthors, err := compilePatterns(authors)
 	if err != nil {
 		return err
 	}
-	commits, err := parseToCommits(output)
+	commits, err := r.logBranch(r.mainRev(), authors, coAuthors, since, until)
+	if err != nil {
+		return err
+	}
+	for _, c := range commits {
+		c.Branches = []string{r.mainBranch()}
+		c.OnMainBranch = true
+		c.Date = inLocation(c.Date, author.Location)
+	}
+
+	branches, err := r.scannedBranches()
 	if err != nil {
 		return err
 	}
+	if len(branches) == 0 {
+		r.Commits = commits
+		return nil
+	}
+
+	byHash := make(map[string]*Commit, len(commits))
+	for _, c := range commits {
+		byHash[c.Hash] = c
+	}
+	for _, branch := range branches {
+		branchCommits, err := r.logBranch(git.RemoteBranchRef(branch), authors, coAuthors, since, until)
+		if err != nil {
+			return fmt.Errorf("branch %s: %w", branch, err)
+		}
+		for _, c := range branchCommits {
+			if seen, ok := byHash[c.Hash]; ok {
+				seen.Branches = append(seen.Branches, branch)
+				continue
+			}
+			c.Branches = []string{branch}
+			c.Date = inLocation(c.Date, author.Location)
+			byHash[c.Hash] = c
+			commits = append(commits, c)
+		}
+	}
+	sortNewestFirst(commits)
 	r.Commits = commits
 	return nil
 }
 
-func (r *Repo) GetCommitsContents() error {
-	for _, commit := range r.Commits {
-		content, err := git.GetCommitContents(r.RepoDir, commit.Hash)
+// inLocation returns t in loc, or t unchanged when loc is nil.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t
+	}
+	return t.In(loc)
+}
+
+// mainRev is the revision holding the main branch: HEAD for a cache, which
+// points at it, or the local branch of a working copy, which may have
+// another one checked out.
+func (r *Repo) mainRev() string {
+	if r.Local && r.Branch != "" {
+		return "refs/heads/" + r.Branch
+	}
+	return ""
+}
+
+func (r *Repo) mainBranch() string {
+	if r.Branch == "" {
+		return "HEAD"
+	}
+	return r.Branch
+}
+
+// logBranch lists the commits on rev authored by any of the authors, plus
+// the ones whose Co-authored-by trailers match coAuthors, marked as such.
+func (r *Repo) logBranch(rev string, authors []string, coAuthors []*regexp.Regexp, since, until time.Time) ([]*Commit, error) {
+	output, err := r.backend.GetCommitsByAuthor(r.RepoDir, rev, authors, since, until)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := parseToCommits(output)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err = r.backend.GetCoAuthoredCommits(r.RepoDir, rev, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("co-authored commits: %w", err)
+	}
+	coAuthored, err := parseToCommits(output)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, c := range coAuthored {
+		if slices.ContainsFunc(c.CoAuthors(), func(ident string) bool { return matchAny(coAuthors, ident) }) {
+			c.CoAuthored = true
+			commits = append(commits, c)
+			found = true
+		}
+	}
+	// commits both authored and co-authored keep the authored entry
+	commits = uniqueCommits(commits)
+	if found {
+		sortNewestFirst(commits)
+	}
+	return commits, nil
+}
+
+// sortNewestFirst restores the newest-first order of a single git log after
+// merging several of them.
+func sortNewestFirst(commits []*Commit) {
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Date.After(commits[j].Date)
+	})
+}
+
+// compilePatterns compiles git --author patterns to match co-author trailers
+// the same way, ignoring case.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := git.CompileAuthorPattern(pattern)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		commit.Content = content
+		compiled[i] = re
 	}
-	return nil
+	return compiled, nil
 }
 
-func (rm *RepoManager) Repos() []*Repo {
-	return rm.repos
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	return slices.ContainsFunc(patterns, func(p *regexp.Regexp) bool { return p.MatchString(s) })
+}
+
+// scannedBranches returns the remote branches selected by r.Branches,
+// except the main branch which is always scanned.
+func (r *Repo) scannedBranches() ([]string, error) {
+	if len(r.Branches) == 0 {
+		return nil, nil
+	}
+	remote, err := r.backend.RemoteBranches(r.RepoDir)
+	if err != nil {
+		return nil, fmt.Errorf("list branches: %w", err)
+	}
+
+	var branches []string
+	for _, branch := range remote {
+		if branch != r.Branch && matchBranch(r.Branches, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+func matchBranch(patterns []string, branch string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// authorPatterns turns the author's identities into git --author patterns.
+// Emails and names must match whole, not as a substring of another identity.
+func authorPatterns(author config.UserConfig) []string {
+	var patterns []string
+	for _, email := range append([]string{author.Email}, author.Emails...) {
+		if email != "" {
+			patterns = append(patterns, "<"+regexp.QuoteMeta(email)+">")
+		}
+	}
+	for _, name := range author.Names {
+		if name != "" {
+			patterns = append(patterns, "^"+regexp.QuoteMeta(name)+" <")
+		}
+	}
+	return append(patterns, author.Patterns...)
+}
+
+// uniqueCommits drops repeated hashes, keeping the first occurrence.
+func uniqueCommits(commits []*Commit) []*Commit {
+	seen := make(map[string]bool, len(commits))
+	unique := commits[:0]
+	for _, c := range commits {
+		if seen[c.Hash] {
+			continue
+		}
+		seen[c.Hash] = true
+		unique = append(unique, c)
+	}
+	return unique
+}
+
+// GetCommitsContents fetches the diff and diff statistics of every commit,
+// working on up to the repo's parallelism commits at once. Repos created by
+// a RepoManager share its limit on git operations.
+func (r *Repo) GetCommitsContents() error {
+	return forEach(len(r.Commits), r.parallelism, func(i int) error {
+		commit := r.Commits[i]
+		content, err := r.backend.GetCommitContents(r.RepoDir, commit.Hash)
+		if err != nil {
+			return fmt.Errorf("diff %s: %w", commit.Hash, err)
+		}
+		commit.Content = content
+
+		numstat, err := r.backend.GetCommitStats(r.RepoDir, commit.Hash)
+		if err != nil {
+			return fmt.Errorf("stats %s: %w", commit.Hash, err)
+		}
+		files, err := parseNumstat(numstat)
+		if err != nil {
+			return fmt.Errorf("stats %s: %w", commit.Hash, err)
+		}
+		commit.Stats = newCommitStats(files)
+		return nil
+	})
 }
 
-func (rm *RepoManager) GetAllCommitsByAuthor(author config.UserConfig, since time.Time) error {
+// Repos returns the repositories that have not failed, in config order.
+func (rm *RepoManager) Repos() []*Repo {
+	repos := make([]*Repo, 0, len(rm.repos))
 	for _, repo := range rm.repos {
-		if err := repo.GetCommitsByAuthor(author, since); err != nil {
-			return err
+		if !repo.Status.Failed() {
+			repos = append(repos, repo)
 		}
-		if err := repo.GetCommitsContents(); err != nil {
-			return err
+	}
+	return repos
+}
+
+// Statuses returns the sync status of every configured repository, including
+// the ones that failed, in config order.
+func (rm *RepoManager) Statuses() []RepoStatus {
+	statuses := make([]RepoStatus, len(rm.repos))
+	for i, repo := range rm.repos {
+		statuses[i] = repo.Status
+	}
+	return statuses
+}
+
+// GetAllCommitsByAuthor collects the author's commits and their diffs for
+// every healthy repo. A repo that fails is marked as failed and dropped from
+// Repos; all failures are returned joined per repo. An author who cannot be
+// matched fails the call without touching any repo.
+func (rm *RepoManager) GetAllCommitsByAuthor(author config.UserConfig, since, until time.Time) error {
+	if err := author.Validate(); err != nil {
+		return fmt.Errorf("author: %w", err)
+	}
+	repos := rm.Repos()
+	return forEach(len(repos), rm.parallelism, func(i int) error {
+		return repos[i].collect(author, since, until)
+	})
+}
+
+// CollectCommitsByAuthor returns a copy of every healthy repo holding only
+// the author's commits and diffs. The managed repos keep their own commits
+// and status, so a team run can call it once per member on the same clones.
+// A repo that fails for this author is left out of the result and its
+// failed status returned instead; the error joins these failures per repo.
+// The next author still gets the repo. An author who cannot be matched
+// only fails this call, so the other members are still collected.
+func (rm *RepoManager) CollectCommitsByAuthor(author config.UserConfig, since, until time.Time) ([]*Repo, []RepoStatus, error) {
+	if err := author.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("author: %w", err)
+	}
+	repos := rm.Repos()
+	copies := make([]*Repo, len(repos))
+	err := forEach(len(repos), rm.parallelism, func(i int) error {
+		c := *repos[i]
+		c.Commits = nil
+		copies[i] = &c
+		return copies[i].collect(author, since, until)
+	})
+
+	collected := make([]*Repo, 0, len(copies))
+	var failed []RepoStatus
+	for _, c := range copies {
+		if c.Status.Failed() {
+			failed = append(failed, c.Status)
+			continue
 		}
+		collected = append(collected, c)
+	}
+	return collected, failed, err
+}
+
+// collect fills r with the author's commits and diffs, and marks r as
+// failed when that fails.
+func (r *Repo) collect(author config.UserConfig, since, until time.Time) error {
+	if err := r.GetCommitsByAuthor(author, since, until); err != nil {
+		r.fail(fmt.Errorf("log: %w", err))
+		return &RepoError{Repo: r.Name, Err: r.Status.Err}
+	}
+	if err := r.GetCommitsContents(); err != nil {
+		r.fail(err)
+		return &RepoError{Repo: r.Name, Err: err}
 	}
 	return nil
 }
@@ -1,12 +1,19 @@
 package repo
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/pkg/git"
 )
 
 func TestClone(t *testing.T) {
@@ -30,10 +37,9 @@ func TestClone(t *testing.T) {
 		t.Fatalf("Clone() failed: %v", err)
 	}
 
-	// Verify the repo was cloned by checking if .git directory exists
-	gitDir := filepath.Join(repo.RepoDir, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		t.Errorf("Clone() did not create .git directory at %s", gitDir)
+	// Verify the repo was cached as a bare repository
+	if !git.IsBareRepository(repo.RepoDir) {
+		t.Errorf("Clone() did not create a bare cache at %s", repo.RepoDir)
 	}
 }
 
@@ -44,7 +50,7 @@ func TestRepoManager_CloneAll(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	rm := NewRepoManager(tmpDir)
+	rm := NewRepoManager(tmpDir, 2, nil)
 
 	reposConfig := config.ReposConfig{
 		TargetRepos: []config.RepoConfig{
@@ -62,8 +68,8 @@ func TestRepoManager_CloneAll(t *testing.T) {
 	}
 
 	// Verify the repo was cloned
-	repoDir := filepath.Join(tmpDir, "hello-world", ".git")
-	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
+	repoDir := filepath.Join(tmpDir, "github.com", "octocat", "Hello-World.git")
+	if !git.IsBareRepository(repoDir) {
 		t.Errorf("CloneAll() did not clone repo to %s", repoDir)
 	}
 }
@@ -96,7 +102,7 @@ func TestGetCommitsByAuthor(t *testing.T) {
 	// Use a date far in the past to ensure we get commits
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	err = repo.GetCommitsByAuthor(author, since)
+	err = repo.GetCommitsByAuthor(author, since, time.Time{})
 	if err != nil {
 		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
 	}
@@ -138,7 +144,7 @@ func TestGetCommitsByAuthor_NoCommits(t *testing.T) {
 
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	err = repo.GetCommitsByAuthor(author, since)
+	err = repo.GetCommitsByAuthor(author, since, time.Time{})
 	if err != nil {
 		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
 	}
@@ -147,3 +153,972 @@ func TestGetCommitsByAuthor_NoCommits(t *testing.T) {
 		t.Errorf("Expected 0 commits for nonexistent author, got %d", len(repo.Commits))
 	}
 }
+
+// newFixtureRepo creates a local git repository on branch main with one
+// commit per message, all authored by email, and returns its path.
+func newFixtureRepo(t *testing.T, email string, messages ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Fixture Author", "GIT_AUTHOR_EMAIL="+email,
+			"GIT_COMMITTER_NAME=Fixture Author", "GIT_COMMITTER_EMAIL="+email,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	runGit("init", "-q", "-b", "main")
+	for i, msg := range messages {
+		file := fmt.Sprintf("file%d.txt", i)
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(msg+"\n"), 0644); err != nil {
+			t.Fatalf("failed to write fixture file: %v", err)
+		}
+		runGit("add", file)
+		runGit("commit", "-q", "-m", msg)
+	}
+	return dir
+}
+
+func TestRepoManager_ConcurrentCollection(t *testing.T) {
+	email := "fixture@example.com"
+	reposConfig := config.ReposConfig{}
+	for i := range 6 {
+		src := newFixtureRepo(t, email, fmt.Sprintf("repo %d first", i), fmt.Sprintf("repo %d second", i))
+		reposConfig.TargetRepos = append(reposConfig.TargetRepos, config.RepoConfig{
+			Name:   fmt.Sprintf("repo-%d", i),
+			URL:    src,
+			Branch: "main",
+		})
+	}
+
+	rm := NewRepoManager(t.TempDir(), 3, nil)
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+
+	author := config.UserConfig{Email: email}
+	if err := rm.GetAllCommitsByAuthor(author, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetAllCommitsByAuthor() failed: %v", err)
+	}
+
+	repos := rm.Repos()
+	if len(repos) != 6 {
+		t.Fatalf("Expected 6 repos, got %d", len(repos))
+	}
+	for i, r := range repos {
+		if want := fmt.Sprintf("repo-%d", i); r.Name != want {
+			t.Errorf("Repos()[%d] = %s, want %s (order should follow config)", i, r.Name, want)
+		}
+		if len(r.Commits) != 2 {
+			t.Errorf("%s: expected 2 commits, got %d", r.Name, len(r.Commits))
+			continue
+		}
+		for _, c := range r.Commits {
+			if c.Content == "" {
+				t.Errorf("%s: commit %s has no content", r.Name, c.Hash[:7])
+			}
+		}
+	}
+}
+
+func TestRepoManager_CloneAllAggregatesErrors(t *testing.T) {
+	src := newFixtureRepo(t, "fixture@example.com", "only commit")
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	reposConfig := config.ReposConfig{
+		TargetRepos: []config.RepoConfig{
+			{Name: "broken-a", URL: missing + "-a", Branch: "main"},
+			{Name: "good", URL: src, Branch: "main"},
+			{Name: "broken-b", URL: missing + "-b", Branch: "main"},
+		},
+	}
+
+	rm := NewRepoManager(t.TempDir(), 2, nil)
+	err := rm.CloneAll(reposConfig)
+	if err == nil {
+		t.Fatal("CloneAll() should report the failing repos")
+	}
+
+	var repoErr *RepoError
+	if !errors.As(err, &repoErr) {
+		t.Fatalf("Expected a *RepoError, got %T: %v", err, err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("Expected 2 joined errors, got %d: %v", n, err)
+	}
+
+	if len(rm.Repos()) != 1 || rm.Repos()[0].Name != "good" {
+		t.Errorf("Expected only the good repo to be kept, got %v", rm.Repos())
+	}
+
+	wantStates := []SyncState{StateFailed, StateCloned, StateFailed}
+	statuses := rm.Statuses()
+	if len(statuses) != len(wantStates) {
+		t.Fatalf("Expected %d statuses, got %d", len(wantStates), len(statuses))
+	}
+	for i, status := range statuses {
+		if status.State != wantStates[i] {
+			t.Errorf("%s: state = %q, want %q", status.Name, status.State, wantStates[i])
+		}
+		if status.Failed() && status.Err == nil {
+			t.Errorf("%s: failed status should carry a reason", status.Name)
+		}
+	}
+}
+
+func TestRepoManager_StatusesTrackPullAndLogFailures(t *testing.T) {
+	email := "fixture@example.com"
+	baseDir := t.TempDir()
+	reposConfig := config.ReposConfig{
+		TargetRepos: []config.RepoConfig{
+			{Name: "kept", URL: newFixtureRepo(t, email, "first"), Branch: "main"},
+			{Name: "corrupted", URL: newFixtureRepo(t, email, "first"), Branch: "main"},
+		},
+	}
+
+	if err := NewRepoManager(baseDir, 2, nil).CloneAll(reposConfig); err != nil {
+		t.Fatalf("first CloneAll() failed: %v", err)
+	}
+
+	rm := NewRepoManager(baseDir, 2, nil)
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("second CloneAll() failed: %v", err)
+	}
+	for _, status := range rm.Statuses() {
+		if status.State != StatePulled {
+			t.Errorf("%s: state = %q, want %q", status.Name, status.State, StatePulled)
+		}
+	}
+
+	// break the history of one clone so that git log fails for it only
+	if err := os.RemoveAll(filepath.Join(rm.Repos()[1].RepoDir, "objects")); err != nil {
+		t.Fatalf("failed to corrupt fixture: %v", err)
+	}
+
+	err := rm.GetAllCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{})
+	if err == nil {
+		t.Fatal("GetAllCommitsByAuthor() should report the corrupted repo")
+	}
+
+	repos := rm.Repos()
+	if len(repos) != 1 || repos[0].Name != "kept" || len(repos[0].Commits) != 1 {
+		t.Errorf("Expected only the healthy repo with its commit, got %v", repos)
+	}
+	if status := rm.Statuses()[1]; !status.Failed() {
+		t.Errorf("corrupted repo should be marked failed, got %q", status.State)
+	}
+}
+
+// logRecord formats a record the way git.Backend returns them.
+func logRecord(hash, author string, timestamp int64, message string) string {
+	return hash + "\x00" + author + "\x00" + fmt.Sprint(timestamp) + "\x00" + message + "\x00"
+}
+
+const (
+	hashA = "0123456789abcdef0123456789abcdef01234567"
+	hashB = "89abcdef0123456789abcdef0123456789abcdef"
+)
+
+func TestParseToCommits_ArbitraryCharacters(t *testing.T) {
+	output := []byte(logRecord(hashA, "Jane ||| Doe\n", 1709467200, "Split a|||b in subject\x1e\t"))
+
+	commits, err := parseToCommits(output)
+	if err != nil {
+		t.Fatalf("parseToCommits() failed: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("Expected 1 commit, got %d", len(commits))
+	}
+	if commits[0].Author != "Jane ||| Doe\n" {
+		t.Errorf("Author = %q", commits[0].Author)
+	}
+	if commits[0].Message != "Split a|||b in subject\x1e" {
+		t.Errorf("Message = %q, want %q", commits[0].Message, "Split a|||b in subject\x1e")
+	}
+}
+
+func TestParseToCommits_BodyAndTrailers(t *testing.T) {
+	output := []byte(logRecord(hashA, "Jane Doe", 1709467200, "Fix login\nredirect loop\n\nThe session cookie was dropped\non redirect.\n\n"+
+		"Co-authored-by: John Roe <john@example.com>\nReviewed-by: Ann Poe\n <ann@example.com>\n") +
+		logRecord(hashB, "Jane Doe", 1709380800, "docs: typo"))
+
+	commits, err := parseToCommits(output)
+	if err != nil {
+		t.Fatalf("parseToCommits() failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+
+	c := commits[0]
+	if c.Message != "Fix login redirect loop" {
+		t.Errorf("Message = %q", c.Message)
+	}
+	if c.Body != "The session cookie was dropped\non redirect." {
+		t.Errorf("Body = %q", c.Body)
+	}
+	want := []Trailer{
+		{Key: "Co-authored-by", Value: "John Roe <john@example.com>"},
+		{Key: "Reviewed-by", Value: "Ann Poe <ann@example.com>"},
+	}
+	if fmt.Sprint(c.Trailers) != fmt.Sprint(want) {
+		t.Errorf("Trailers = %+v, want %+v", c.Trailers, want)
+	}
+	if got := c.CoAuthors(); len(got) != 1 || got[0] != "John Roe <john@example.com>" {
+		t.Errorf("CoAuthors() = %q", got)
+	}
+
+	if c := commits[1]; c.Message != "docs: typo" || c.Body != "" || c.Trailers != nil {
+		t.Errorf("a subject that looks like a trailer must stay the subject: %+v", c)
+	}
+}
+
+func TestParseToCommits_ReportsMalformedRecords(t *testing.T) {
+	valid := logRecord(hashA, "Jane Doe", 1709467200, "Fine")
+	tests := []struct {
+		name   string
+		output string
+		record int
+		text   string
+	}{
+		{"bad timestamp", valid + hashB + "\x00Jane Doe\x00yesterday\x00Broken\x00", 1, "yesterday"},
+		{"bad hash", logRecord("not-a-hash", "Jane Doe", 1709467200, "Broken"), 0, "not-a-hash"},
+		{"missing fields", valid + hashB + "\x00Jane Doe\x00", 1, hashB},
+		{"unterminated", valid + strings.TrimSuffix(logRecord(hashB, "Jane Doe", 1709467200, "Cut off"), "\x00"), 1, "Cut off"},
+		{"old format", "abc123|||Jane Doe|||1709467200|||Subject\n", 0, "|||Subject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := parseToCommits([]byte(tt.output))
+			if err == nil {
+				t.Fatalf("parseToCommits() should fail, got %+v", commits)
+			}
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("error should be a *ParseError, got: %v", err)
+			}
+			if parseErr.Record != tt.record || !strings.Contains(parseErr.Text, tt.text) {
+				t.Errorf("error should point at record %d containing %q, got: %v", tt.record, tt.text, err)
+			}
+		})
+	}
+
+	if commits, err := parseToCommits(nil); err != nil || len(commits) != 0 {
+		t.Errorf("empty output should parse to no commits, got %v, %v", commits, err)
+	}
+}
+
+func FuzzParseToCommits(f *testing.F) {
+	f.Add([]byte(logRecord(hashA, "Jane Doe", 1709467200, "Subject\n\nBody\n\nCo-authored-by: John <john@example.com>\n")))
+	f.Add([]byte(logRecord(hashA, "", 0, "") + logRecord(hashB, "x", -1, "\n\n\n")))
+	f.Add([]byte("abc123|||Jane Doe|||1709467200|||Subject\n"))
+	f.Add([]byte("\x00\x00\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, output []byte) {
+		commits, err := parseToCommits(output)
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("parse failures must be *ParseError, got %T: %v", err, err)
+			}
+			return
+		}
+		if want := strings.Count(string(output), "\x00") / 4; len(commits) != want {
+			t.Fatalf("parsed %d commits from %d records", len(commits), want)
+		}
+		for _, c := range commits {
+			if strings.Contains(c.Message, "\n") {
+				t.Errorf("subject spans several lines: %q", c.Message)
+			}
+		}
+	})
+}
+
+func FuzzParseToCommits_RoundTrip(f *testing.F) {
+	f.Add("Jane Doe", int64(1709467200), "Subject\n\nBody")
+	f.Add("Jane ||| Doe", int64(-1), "Split a|||b\x1e\n\nReviewed-by: Ann")
+
+	f.Fuzz(func(t *testing.T, author string, timestamp int64, message string) {
+		if strings.Contains(author+message, "\x00") {
+			t.Skip("git refuses NUL in identities and messages")
+		}
+		commits, err := parseToCommits([]byte(logRecord(hashA, author, timestamp, message) + logRecord(hashB, "Jane", 0, "Next")))
+		if err != nil {
+			t.Fatalf("parseToCommits() failed: %v", err)
+		}
+		if len(commits) != 2 || commits[0].Author != author || commits[0].Date.Unix() != timestamp || commits[1].Hash != hashB {
+			t.Fatalf("round trip lost data: %+v", commits)
+		}
+	})
+}
+
+func TestGetCommitsByAuthor_IncludesCoAuthoredCommits(t *testing.T) {
+	src := newFixtureRepo(t, "other@example.com",
+		"Pair on parser\n\nCo-authored-by: Jane Doe <jane@example.com>",
+		"Solo work\n\nReviewed-by: Jane Doe <jane@example.com>",
+		"Pair with someone else\n\nCo-authored-by: John Roe <john@example.com>",
+	)
+	repo := NewRepo("fixture", src, "main", src)
+
+	author := config.UserConfig{Email: "jane@example.com"}
+	if err := repo.GetCommitsByAuthor(author, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(repo.Commits) != 1 {
+		t.Fatalf("Expected only the co-authored commit, got %d", len(repo.Commits))
+	}
+	if c := repo.Commits[0]; c.Message != "Pair on parser" || !c.CoAuthored {
+		t.Errorf("unexpected commit: %+v", c)
+	}
+
+	// the author of a co-authored commit sees it as their own
+	if err := repo.GetCommitsByAuthor(config.UserConfig{Email: "other@example.com"}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	for _, c := range repo.Commits {
+		if c.CoAuthored {
+			t.Errorf("authored commit %q should not be marked co-authored", c.Message)
+		}
+	}
+}
+
+func TestGetCommitsByAuthor_MultipleIdentities(t *testing.T) {
+	src := newFixtureRepo(t, "jane@personal.dev", "from laptop", "from desktop")
+	repo := NewRepo("fixture", src, "main", src)
+
+	author := config.UserConfig{
+		Email:    "jane@example.com",
+		Emails:   []string{"JANE@personal.dev"},
+		Names:    []string{"Fixture Author"},
+		Patterns: []string{`personal\.dev`},
+	}
+	if err := repo.GetCommitsByAuthor(author, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(repo.Commits) != 2 {
+		t.Fatalf("Expected 2 commits matched by several identities, got %d", len(repo.Commits))
+	}
+
+	// the primary address must not match a longer one containing it
+	other := config.UserConfig{Email: "e@personal.dev"}
+	if err := repo.GetCommitsByAuthor(other, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(repo.Commits) != 0 {
+		t.Errorf("Expected no commits for a different address, got %d", len(repo.Commits))
+	}
+}
+
+func TestGetCommitsByAuthor_DatesInAuthorLocation(t *testing.T) {
+	src := newFixtureRepo(t, "jane@example.com", "first")
+	repo := NewRepo("fixture", src, "main", src)
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	author := config.UserConfig{Email: "jane@example.com", Location: tokyo}
+	if err := repo.GetCommitsByAuthor(author, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(repo.Commits) != 1 {
+		t.Fatalf("Expected 1 commit, got %d", len(repo.Commits))
+	}
+	if loc := repo.Commits[0].Date.Location(); loc != tokyo {
+		t.Errorf("Commit date in %v, want %v", loc, tokyo)
+	}
+}
+
+func TestAuthorPatterns(t *testing.T) {
+	got := authorPatterns(config.UserConfig{
+		Email:    "jane+work@example.com",
+		Emails:   []string{"", "1+jane@users.noreply.github.com"},
+		Names:    []string{"Jane (old)"},
+		Patterns: []string{"^J\\. Doe"},
+	})
+	want := []string{
+		`<jane\+work@example\.com>`,
+		`<1\+jane@users\.noreply\.github\.com>`,
+		`^Jane \(old\) <`,
+		`^J\. Doe`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("authorPatterns() = %q, want %q", got, want)
+	}
+}
+
+func TestUniqueCommits(t *testing.T) {
+	commits := []*Commit{{Hash: "a"}, {Hash: "b"}, {Hash: "a"}, {Hash: "c"}, {Hash: "b"}}
+	got := uniqueCommits(commits)
+	if len(got) != 3 || got[0].Hash != "a" || got[1].Hash != "b" || got[2].Hash != "c" {
+		t.Errorf("uniqueCommits() = %v", got)
+	}
+}
+
+func TestRepoManager_CollectCommitsByAuthorPerMember(t *testing.T) {
+	src := newFixtureRepo(t, "jane@example.com", "jane first", "jane second")
+	cmd := exec.Command("git", "-C", src, "commit", "-q", "--allow-empty", "-m", "joe's work")
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Joe", "GIT_AUTHOR_EMAIL=joe@example.com",
+		"GIT_COMMITTER_NAME=Joe", "GIT_COMMITTER_EMAIL=joe@example.com",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+
+	rm := NewRepoManager(t.TempDir(), 2, nil)
+	reposConfig := config.ReposConfig{TargetRepos: []config.RepoConfig{{Name: "shared", URL: src, Branch: "main"}}}
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	jane, _, err := rm.CollectCommitsByAuthor(config.UserConfig{Email: "jane@example.com"}, since, time.Time{})
+	if err != nil {
+		t.Fatalf("CollectCommitsByAuthor(jane) failed: %v", err)
+	}
+	joe, _, err := rm.CollectCommitsByAuthor(config.UserConfig{Email: "joe@example.com"}, since, time.Time{})
+	if err != nil {
+		t.Fatalf("CollectCommitsByAuthor(joe) failed: %v", err)
+	}
+
+	if len(jane) != 1 || len(jane[0].Commits) != 2 {
+		t.Errorf("Expected 2 commits for jane, got %+v", jane)
+	}
+	if len(joe) != 1 || len(joe[0].Commits) != 1 || joe[0].Commits[0].Message != "joe's work" {
+		t.Errorf("Expected joe's single commit, got %+v", joe)
+	}
+	if jane[0] == rm.Repos()[0] || len(rm.Repos()[0].Commits) != 0 {
+		t.Error("CollectCommitsByAuthor() should work on copies and leave the managed repos untouched")
+	}
+}
+
+func TestRepoManager_CollectCommitsByAuthorKeepsReposForOtherMembers(t *testing.T) {
+	src := newFixtureRepo(t, "jane@example.com", "jane first")
+	rm := NewRepoManager(t.TempDir(), 2, nil)
+	reposConfig := config.ReposConfig{TargetRepos: []config.RepoConfig{{Name: "shared", URL: src, Branch: "main"}}}
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	for _, member := range []config.UserConfig{
+		{FullName: "Name Only"},
+		{Email: "bad@example.com", Patterns: []string{"("}},
+	} {
+		if _, _, err := rm.CollectCommitsByAuthor(member, since, time.Time{}); err == nil {
+			t.Errorf("CollectCommitsByAuthor(%+v) should fail", member)
+		}
+	}
+	if rm.Repos()[0].Status.Failed() {
+		t.Fatalf("an unmatchable member should not fail the repo: %v", rm.Repos()[0].Status.Err)
+	}
+
+	jane, _, err := rm.CollectCommitsByAuthor(config.UserConfig{Email: "jane@example.com"}, since, time.Time{})
+	if err != nil {
+		t.Fatalf("CollectCommitsByAuthor(jane) failed: %v", err)
+	}
+	if len(jane) != 1 || len(jane[0].Commits) != 1 {
+		t.Errorf("Expected jane's commit, got %+v", jane)
+	}
+}
+
+// logFailingBackend fails to log the commits of one author.
+type logFailingBackend struct {
+	git.ExecBackend
+	author string
+}
+
+func (b logFailingBackend) GetCommitsByAuthor(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error) {
+	if slices.ContainsFunc(authors, func(a string) bool { return strings.Contains(a, b.author) }) {
+		return nil, errors.New("log exploded")
+	}
+	return b.ExecBackend.GetCommitsByAuthor(repoDir, rev, authors, since, until)
+}
+
+func TestRepoManager_CollectCommitsByAuthorFailsPerMember(t *testing.T) {
+	src := newFixtureRepo(t, "jane@example.com", "jane first")
+	rm := NewRepoManager(t.TempDir(), 2, logFailingBackend{author: "joe"})
+	reposConfig := config.ReposConfig{TargetRepos: []config.RepoConfig{{Name: "shared", URL: src, Branch: "main"}}}
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	joe, failed, err := rm.CollectCommitsByAuthor(config.UserConfig{Email: "joe@example.com"}, since, time.Time{})
+	var repoErr *RepoError
+	if !errors.As(err, &repoErr) || repoErr.Repo != "shared" {
+		t.Fatalf("expected a RepoError for shared, got: %v", err)
+	}
+	if len(joe) != 0 || len(failed) != 1 || failed[0].Name != "shared" || !failed[0].Failed() {
+		t.Fatalf("the repo should be reported as failed for joe, got %+v, %+v", joe, failed)
+	}
+	if rm.Repos()[0].Status.Failed() {
+		t.Fatalf("a failure for one member should not fail the shared repo: %v", rm.Repos()[0].Status.Err)
+	}
+
+	jane, failed, err := rm.CollectCommitsByAuthor(config.UserConfig{Email: "jane@example.com"}, since, time.Time{})
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("CollectCommitsByAuthor(jane) failed: %v, %+v", err, failed)
+	}
+	if len(jane) != 1 || len(jane[0].Commits) != 1 {
+		t.Errorf("Expected jane's commit, got %+v", jane)
+	}
+}
+
+func TestGetCommitsByAuthor_ScansBranches(t *testing.T) {
+	email := "jane@example.com"
+	src := newFixtureRepo(t, email, "main work")
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", src}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane", "GIT_AUTHOR_EMAIL="+email,
+			"GIT_COMMITTER_NAME=Jane", "GIT_COMMITTER_EMAIL="+email,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	runGit("branch", "release")
+	runGit("checkout", "-q", "-b", "feature/login")
+	runGit("commit", "-q", "--allow-empty", "-m", "login work")
+	runGit("checkout", "-q", "-b", "spike", "main")
+	runGit("commit", "-q", "--allow-empty", "-m", "spike work")
+	runGit("checkout", "-q", "main")
+
+	rm := NewRepoManager(t.TempDir(), 2, nil)
+	reposConfig := config.ReposConfig{TargetRepos: []config.RepoConfig{
+		{Name: "app", URL: src, Branch: "main", Branches: []string{"feature/*", "release"}},
+	}}
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	r := rm.Repos()[0]
+	if err := r.GetCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+
+	got := map[string]*Commit{}
+	for _, c := range r.Commits {
+		got[c.Message] = c
+	}
+	if len(r.Commits) != 2 {
+		t.Fatalf("Expected 2 deduplicated commits, got %d: %v", len(r.Commits), got)
+	}
+	if c := got["main work"]; c == nil || !c.OnMainBranch || strings.Join(c.Branches, ",") != "main,feature/login,release" {
+		t.Errorf("main work = %+v, want on every branch containing it", c)
+	}
+	if c := got["login work"]; c == nil || c.OnMainBranch || strings.Join(c.Branches, ",") != "feature/login" {
+		t.Errorf("login work = %+v, want only on feature/login", c)
+	}
+	if _, ok := got["spike work"]; ok {
+		t.Error("branches not selected by the globs should not be scanned")
+	}
+}
+
+func TestMatchBranch(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		branch   string
+		want     bool
+	}{
+		{[]string{"*"}, "feature/login", true},
+		{[]string{"feature/*"}, "feature/login", true},
+		{[]string{"feature/*"}, "feature/a/b", false},
+		{[]string{"release", "hotfix-*"}, "hotfix-12", true},
+		{[]string{"release"}, "main", false},
+	}
+	for _, tt := range tests {
+		if got := matchBranch(tt.patterns, tt.branch); got != tt.want {
+			t.Errorf("matchBranch(%v, %q) = %v, want %v", tt.patterns, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	output := []byte("3\t1\tmain.go\x00-\t-\tlogo.png\x000\t0\t\x00old/name.go\x00new/name.go\x0010\t2\tREADME.md\x00")
+
+	files, err := parseNumstat(output)
+	if err != nil {
+		t.Fatalf("parseNumstat() failed: %v", err)
+	}
+	want := []FileStat{
+		{Path: "main.go", Insertions: 3, Deletions: 1},
+		{Path: "logo.png", Binary: true},
+		{Path: "new/name.go", OldPath: "old/name.go", Renamed: true},
+		{Path: "README.md", Insertions: 10, Deletions: 2},
+	}
+	if fmt.Sprint(files) != fmt.Sprint(want) {
+		t.Errorf("parseNumstat() = %+v, want %+v", files, want)
+	}
+
+	stats := newCommitStats(files)
+	if stats.FilesChanged != 4 || stats.Insertions != 13 || stats.Deletions != 3 {
+		t.Errorf("unexpected totals: %+v", stats)
+	}
+
+	for _, bad := range []string{"3\tmain.go\x00", "x\t1\tmain.go\x00", "1\t0\t\x00old.go\x00"} {
+		if _, err := parseNumstat([]byte(bad)); err == nil {
+			t.Errorf("parseNumstat(%q) should fail", bad)
+		}
+	}
+}
+
+func TestGetCommitsContents_IncludesStats(t *testing.T) {
+	email := "fixture@example.com"
+	src := newFixtureRepo(t, email, "first line", "second line")
+	repo := NewRepo("fixture", src, "main", src)
+
+	if err := repo.GetCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if err := repo.GetCommitsContents(); err != nil {
+		t.Fatalf("GetCommitsContents() failed: %v", err)
+	}
+
+	for _, c := range repo.Commits {
+		if c.Stats.FilesChanged != 1 || c.Stats.Insertions != 1 || c.Stats.Deletions != 0 {
+			t.Errorf("unexpected stats for %q: %+v", c.Message, c.Stats)
+		}
+	}
+}
+
+func TestResolveAuth(t *testing.T) {
+	t.Setenv("REPORT_TEST_TOKEN", " from-env\n")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := resolveAuth(&config.AuthConfig{Username: "ci", TokenEnv: "REPORT_TEST_TOKEN", SSHKey: "/keys/id_ed25519"})
+	if err != nil || auth.Token != "from-env" || auth.Username != "ci" || auth.SSHKey != "/keys/id_ed25519" {
+		t.Errorf("unexpected auth from env: %+v, %v", auth, err)
+	}
+	if auth, err := resolveAuth(&config.AuthConfig{TokenFile: tokenFile}); err != nil || auth.Token != "from-file" {
+		t.Errorf("unexpected auth from file: %+v, %v", auth, err)
+	}
+	if auth, err := resolveAuth(nil); err != nil || auth != (git.Auth{}) {
+		t.Errorf("nil settings should give no credentials, got %+v, %v", auth, err)
+	}
+
+	for _, cfg := range []config.AuthConfig{
+		{TokenEnv: "REPORT_TEST_UNSET_TOKEN"},
+		{TokenFile: filepath.Join(t.TempDir(), "missing")},
+		{TokenEnv: "REPORT_TEST_TOKEN", TokenFile: tokenFile},
+	} {
+		if _, err := resolveAuth(&cfg); err == nil {
+			t.Errorf("resolveAuth(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestClone_AuthSettingsFailTheRepo(t *testing.T) {
+	src := newFixtureRepo(t, "fixture@example.com", "first")
+	rm := NewRepoManager(t.TempDir(), 1, nil)
+	r := rm.NewRepoFromConfig(config.RepoConfig{
+		Name:   "private",
+		URL:    src,
+		Branch: "main",
+		Auth:   &config.AuthConfig{TokenEnv: "REPORT_TEST_UNSET_TOKEN"},
+	})
+
+	err := r.Clone()
+	if err == nil || !strings.Contains(err.Error(), "REPORT_TEST_UNSET_TOKEN") {
+		t.Fatalf("Clone() should fail on the missing token, got: %v", err)
+	}
+	if !r.Status.Failed() {
+		t.Errorf("status should be failed, got %+v", r.Status)
+	}
+}
+
+func TestLocalRepo_ReadsInPlace(t *testing.T) {
+	email := "fixture@example.com"
+	dir := newFixtureRepo(t, email, "on main")
+	runGit := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Fixture Author", "GIT_AUTHOR_EMAIL="+email,
+			"GIT_COMMITTER_NAME=Fixture Author", "GIT_COMMITTER_EMAIL="+email)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	runGit("checkout", "-q", "-b", "wip")
+	runGit("commit", "-q", "--allow-empty", "-m", "work in progress")
+
+	rm := NewRepoManager(t.TempDir(), 1, nil)
+	if err := rm.CloneAll(config.ReposConfig{TargetRepos: []config.RepoConfig{
+		{Path: dir, Branch: "main"},
+		{Name: "checkout", Path: dir + "/"},
+	}}); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	repos := rm.Repos()
+	if len(repos) != 1 {
+		t.Fatalf("the same working copy should be read once, got %d repos", len(repos))
+	}
+	local := repos[0]
+	if local.Name != filepath.Base(dir) || local.Status.State != StateLocal {
+		t.Errorf("unexpected local repo: %+v", local)
+	}
+
+	if err := local.GetCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(local.Commits) != 1 || local.Commits[0].Message != "on main" || local.Commits[0].Branches[0] != "main" {
+		t.Errorf("expected only the main branch commit, got %+v", local.Commits)
+	}
+
+	head := NewLocalRepo("", "", dir)
+	if err := head.GetCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	if len(head.Commits) != 2 {
+		t.Errorf("without a branch HEAD should be read, got %d commits", len(head.Commits))
+	}
+
+	if got := runGit("rev-parse", "--abbrev-ref", "HEAD"); got != "wip" {
+		t.Errorf("the checked out branch must not change, got %q", got)
+	}
+	if got := runGit("remote"); got != "" {
+		t.Errorf("no remote should be involved, got %q", got)
+	}
+}
+
+func TestDiscoverRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"api/.git",
+		"api/vendor/lib/.git", // inside a working copy
+		"team/web/.git",
+		"team/old/.git", // excluded
+		".cache/tool/.git",
+		"deep/a/b/c/.git", // below the depth limit
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repos, err := DiscoverRepos(config.ScanConfig{Root: root, Exclude: []string{"team/old"}})
+	if err != nil {
+		t.Fatalf("DiscoverRepos() failed: %v", err)
+	}
+	var names []string
+	for _, r := range repos {
+		names = append(names, r.Name)
+		if r.Path != filepath.Join(root, r.Name) {
+			t.Errorf("repo %s has path %s", r.Name, r.Path)
+		}
+	}
+	if got := strings.Join(names, ","); got != "api,team/web" {
+		t.Errorf("DiscoverRepos() found %s, want api,team/web", got)
+	}
+
+	if repos, _ := DiscoverRepos(config.ScanConfig{Root: root, MaxDepth: 4}); len(repos) != 4 {
+		t.Errorf("a deeper scan should also find deep/a/b/c, got %+v", repos)
+	}
+}
+
+func TestRepoManager_CloneAllScansRoots(t *testing.T) {
+	root := t.TempDir()
+	email := "fixture@example.com"
+	for _, name := range []string{"one", "two"} {
+		src := newFixtureRepo(t, email, "commit in "+name)
+		if err := os.Rename(src, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	rm := NewRepoManager(t.TempDir(), 2, nil)
+	err := rm.CloneAll(config.ReposConfig{Scan: []config.ScanConfig{{Root: root}, {Root: missing}}})
+	if err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("CloneAll() should report the missing scan root, got: %v", err)
+	}
+	if got := len(rm.Repos()); got != 2 {
+		t.Fatalf("Expected the 2 discovered repos, got %d", got)
+	}
+	if statuses := rm.Statuses(); len(statuses) != 3 || !statuses[0].Failed() {
+		t.Errorf("the scan failure should be listed with the statuses, got %+v", statuses)
+	}
+	if err := rm.GetAllCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetAllCommitsByAuthor() failed: %v", err)
+	}
+	for _, r := range rm.Repos() {
+		if len(r.Commits) != 1 || r.Commits[0].Message != "commit in "+r.Name {
+			t.Errorf("%s: unexpected commits %+v", r.Name, r.Commits)
+		}
+	}
+}
+
+func TestRepoManager_LimitHistory(t *testing.T) {
+	email := "fixture@example.com"
+	src := t.TempDir()
+	commit := func(msg, date string) {
+		t.Helper()
+		cmd := exec.Command("git", "-C", src, "commit", "-q", "--allow-empty", "-m", msg)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Fixture Author", "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=Fixture Author", "GIT_COMMITTER_EMAIL="+email, "GIT_COMMITTER_DATE="+date,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit failed: %v\n%s", err, out)
+		}
+	}
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", src).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	commit("ancient", "2020-01-01T12:00:00Z")
+	commit("before the range", "2024-02-01T12:00:00Z")
+	commit("in range", "2024-03-02T12:00:00Z")
+
+	baseDir := t.TempDir()
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rm := NewRepoManager(baseDir, 1, nil)
+	rm.LimitHistory(since)
+	if err := rm.CloneAll(config.ReposConfig{TargetRepos: []config.RepoConfig{{Name: "app", URL: src, Branch: "main"}}}); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	if err := rm.GetAllCommitsByAuthor(config.UserConfig{Email: email}, since, time.Time{}); err != nil {
+		t.Fatalf("GetAllCommitsByAuthor() failed: %v", err)
+	}
+	if commits := rm.Repos()[0].Commits; len(commits) != 1 || commits[0].Message != "in range" {
+		t.Errorf("unexpected commits %+v", commits)
+	}
+
+	// the commit in range and its parent, nothing older
+	out, err := exec.Command("git", "-C", rm.Repos()[0].RepoDir, "rev-list", "--count", "HEAD").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "2" {
+		t.Errorf("expected 2 fetched commits, got %q, %v", out, err)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	email := "fixture@example.com"
+	baseDir := t.TempDir()
+	reposConfig := config.ReposConfig{Dir: baseDir, TargetRepos: []config.RepoConfig{
+		{Name: "kept", URL: newFixtureRepo(t, email, "kept"), Branch: "main"},
+		{Name: "dropped", URL: newFixtureRepo(t, email, "dropped"), Branch: "main"},
+	}}
+	if err := NewRepoManager(baseDir, 2, nil).CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	gitClone := func(url, dst string) {
+		t.Helper()
+		if out, err := exec.Command("git", "clone", "-q", url, filepath.Join(baseDir, dst)).CombinedOutput(); err != nil {
+			t.Fatalf("git clone failed: %v\n%s", err, out)
+		}
+	}
+	keptURL := reposConfig.TargetRepos[0].URL
+	// a clean working tree cloned by name by earlier versions
+	gitClone(keptURL, "kept")
+	// top-level working trees that may hold someone's work: uncommitted or
+	// unpushed changes, and a remote that was never cached
+	gitClone(keptURL, "edited")
+	if err := os.WriteFile(filepath.Join(baseDir, "edited", "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitClone(keptURL, "unpushed")
+	if out, err := exec.Command("git", "-C", filepath.Join(baseDir, "unpushed"),
+		"-c", "user.name=Fixture", "-c", "user.email="+email,
+		"commit", "-q", "--allow-empty", "-m", "local only").CombinedOutput(); err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+	gitClone(newFixtureRepo(t, email, "elsewhere"), "elsewhere")
+	// working trees that are not ours: nested ones, a configured local
+	// repository and one found under a scan root
+	gitClone(keptURL, filepath.Join("nested", "checkout"))
+	gitClone(keptURL, "local")
+	gitClone(keptURL, filepath.Join("scanned", "project"))
+	if err := os.WriteFile(filepath.Join(baseDir, "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := config.ReposConfig{
+		Dir: baseDir,
+		TargetRepos: []config.RepoConfig{
+			reposConfig.TargetRepos[0],
+			{Name: "local", Path: filepath.Join(baseDir, "local")},
+		},
+		Scan: []config.ScanConfig{{Root: filepath.Join(baseDir, "scanned")}},
+	}
+	dropped := filepath.FromSlash(git.CacheDir(reposConfig.TargetRepos[1].URL))
+	want := []string{"kept", dropped}
+	sort.Strings(want)
+	wantSkipped := []string{"edited", "elsewhere", filepath.Join("nested", "checkout"), "unpushed"}
+	stale, skipped, err := PruneCache(kept, true)
+	if err != nil || strings.Join(stale, ",") != strings.Join(want, ",") {
+		t.Fatalf("PruneCache(dry run) = %v, %v, want %v", stale, err, want)
+	}
+	if strings.Join(skipped, ",") != strings.Join(wantSkipped, ",") {
+		t.Errorf("PruneCache(dry run) skipped %v, want %v", skipped, wantSkipped)
+	}
+	if !git.IsBareRepository(filepath.Join(baseDir, dropped)) {
+		t.Fatal("a dry run must not remove anything")
+	}
+
+	if stale, _, err = PruneCache(kept, false); err != nil || len(stale) != 2 {
+		t.Fatalf("PruneCache() = %v, %v", stale, err)
+	}
+	for _, name := range want {
+		if _, err := os.Stat(filepath.Join(baseDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed: %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		filepath.FromSlash(git.CacheDir(kept.TargetRepos[0].URL)),
+		"notes.txt",
+		filepath.Join("edited", "notes.txt"),
+		"unpushed",
+		"elsewhere",
+		filepath.Join("nested", "checkout"),
+		"local",
+		filepath.Join("scanned", "project"),
+	} {
+		if _, err := os.Stat(filepath.Join(baseDir, name)); err != nil {
+			t.Errorf("%s should be kept: %v", name, err)
+		}
+	}
+
+	kept.Dir = filepath.Join(baseDir, "missing")
+	if stale, _, err := PruneCache(kept, false); err != nil || len(stale) != 0 {
+		t.Errorf("a missing cache directory has nothing to prune, got %v, %v", stale, err)
+	}
+}
+
+func TestRepoManager_CacheKeyedByURL(t *testing.T) {
+	email := "fixture@example.com"
+	baseDir := filepath.Join(t.TempDir(), "repos")
+	reposConfig := config.ReposConfig{TargetRepos: []config.RepoConfig{
+		{Name: "../../escape", URL: newFixtureRepo(t, email, "first"), Branch: "main"},
+	}}
+
+	rm := NewRepoManager(baseDir, 1, nil)
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	repoDir := rm.Repos()[0].RepoDir
+	if rel, err := filepath.Rel(baseDir, repoDir); err != nil || strings.HasPrefix(rel, "..") {
+		t.Fatalf("cache %s escapes %s", repoDir, baseDir)
+	}
+
+	// the same name pointed at another remote gets a cache of its own
+	reposConfig.TargetRepos[0].URL = newFixtureRepo(t, email, "second")
+	rm = NewRepoManager(baseDir, 1, nil)
+	if err := rm.CloneAll(reposConfig); err != nil {
+		t.Fatalf("CloneAll() failed: %v", err)
+	}
+	if r := rm.Repos()[0]; r.RepoDir == repoDir || r.Status.State != StateCloned {
+		t.Errorf("expected a new cache, got %s (%s)", r.RepoDir, r.Status.State)
+	}
+	if err := rm.GetAllCommitsByAuthor(config.UserConfig{Email: email}, time.Now().Add(-time.Hour), time.Time{}); err != nil {
+		t.Fatalf("GetAllCommitsByAuthor() failed: %v", err)
+	}
+	if commits := rm.Repos()[0].Commits; len(commits) != 1 || commits[0].Message != "second" {
+		t.Errorf("unexpected commits %+v", commits)
+	}
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FileStat describes how a commit changed one file. Binary files carry no
+// line counts.
+type FileStat struct {
+	Path       string `json:"path"`
+	OldPath    string `json:"old_path,omitempty"` // set for renames
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+	Renamed    bool   `json:"renamed,omitempty"`
+}
+
+// CommitStats sums up the files a commit changed.
+type CommitStats struct {
+	FilesChanged int        `json:"files_changed"`
+	Insertions   int        `json:"insertions"`
+	Deletions    int        `json:"deletions"`
+	Files        []FileStat `json:"files,omitempty"`
+}
+
+func newCommitStats(files []FileStat) CommitStats {
+	stats := CommitStats{FilesChanged: len(files), Files: files}
+	for _, f := range files {
+		stats.Insertions += f.Insertions
+		stats.Deletions += f.Deletions
+	}
+	return stats
+}
+
+// parseNumstat parses the output of git diff --numstat -z as produced by
+// git.Backend.GetCommitStats.
+func parseNumstat(output []byte) ([]FileStat, error) {
+	var files []FileStat
+	fields := bytes.Split(bytes.TrimSuffix(output, []byte{0}), []byte{0})
+	for i := 0; i < len(fields); i++ {
+		record := string(fields[i])
+		if record == "" {
+			continue
+		}
+
+		added, rest, ok1 := strings.Cut(record, "\t")
+		deleted, path, ok2 := strings.Cut(rest, "\t")
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("malformed numstat record %q", record)
+		}
+
+		var f FileStat
+		if added == "-" && deleted == "-" {
+			f.Binary = true
+		} else {
+			var err error
+			if f.Insertions, err = strconv.Atoi(added); err != nil {
+				return nil, fmt.Errorf("malformed numstat record %q: %w", record, err)
+			}
+			if f.Deletions, err = strconv.Atoi(deleted); err != nil {
+				return nil, fmt.Errorf("malformed numstat record %q: %w", record, err)
+			}
+		}
+
+		// a rename leaves the path empty and follows with the old and new
+		// paths as separate fields
+		if path == "" {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("truncated numstat rename record %q", record)
+			}
+			f.OldPath, path = string(fields[i+1]), string(fields[i+2])
+			f.Renamed = true
+			i += 2
+		}
+		f.Path = path
+		files = append(files, f)
+	}
+	return files, nil
+}
@@ -1,6 +1,7 @@
 package report
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -15,15 +16,41 @@ const (
 )
 
 type RepoCommits struct {
-	RepoName string
-	Commits  []repo.Commit
+	RepoName string        `json:"repo"`
+	Commits  []repo.Commit `json:"commits"`
+	Stats    DiffStats     `json:"stats"`
 }
 
+// RepoFailure describes a repository that could not be read for the report.
+type RepoFailure struct {
+	RepoName string `json:"repo"`
+	URL      string `json:"url"`
+	Reason   string `json:"reason"`
+}
+
+// Report covers the commits made at or after StartDate and before EndDate.
+// Its markdown shows dates in the author's Location, or in the location of
+// StartDate when the author has none.
 type Report struct {
-	Author    config.UserConfig
-	StartDate time.Time
-	EndDate   time.Time
-	Repos     []RepoCommits
+	Author    config.UserConfig `json:"author"`
+	StartDate time.Time         `json:"start_date"`
+	EndDate   time.Time         `json:"end_date"`
+	Repos     []RepoCommits     `json:"repos"`
+	Stats     DiffStats         `json:"stats"`
+	Failures  []RepoFailure     `json:"failures,omitempty"`
+}
+
+// formatPeriod renders the days of the window [start, end) in loc.
+func formatPeriod(start, end time.Time, loc *time.Location) string {
+	return start.In(loc).Format(dateFormat) + " - " + end.Add(-time.Nanosecond).In(loc).Format(dateFormat)
+}
+
+// location returns the zone the report's dates are shown in.
+func (r *Report) location() *time.Location {
+	if r.Author.Location != nil {
+		return r.Author.Location
+	}
+	return r.StartDate.Location()
 }
 
 func NewReport(author config.UserConfig, startDate, endDate time.Time) *Report {
@@ -36,20 +63,47 @@ func NewReport(author config.UserConfig, startDate, endDate time.Time) *Report {
 }
 
 func (r *Report) AddRepoCommits(repoName string, commits []repo.Commit) {
-	r.Repos = append(r.Repos, RepoCommits{
+	rc := RepoCommits{
 		RepoName: repoName,
 		Commits:  commits,
+	}
+	for _, c := range commits {
+		rc.Stats.addCommit(c)
+	}
+	r.Stats.merge(repoName+"/", rc.Stats)
+	r.Repos = append(r.Repos, rc)
+}
+
+func (r *Report) AddFailure(repoName, url, reason string) {
+	r.Failures = append(r.Failures, RepoFailure{
+		RepoName: repoName,
+		URL:      url,
+		Reason:   reason,
 	})
 }
 
+// FailuresToMarkdown renders the repositories that could not be read, or an
+// empty string when every repository was read.
+func (r *Report) FailuresToMarkdown() string {
+	if len(r.Failures) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Repositories that could not be read\n\n")
+	for _, f := range r.Failures {
+		sb.WriteString(fmt.Sprintf("- **%s** (%s): %s\n", f.RepoName, f.URL, f.Reason))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
 func (r *Report) ToMarkdown() string {
 	var sb strings.Builder
 
 	sb.WriteString("# Work Report\n\n")
 	sb.WriteString(fmt.Sprintf("**Author:** %s (%s)\n\n", r.Author.FullName, r.Author.Email))
-	sb.WriteString(fmt.Sprintf("**Period:** %s - %s\n\n",
-		r.StartDate.Format(dateFormat),
-		r.EndDate.Format(dateFormat)))
+	sb.WriteString(fmt.Sprintf("**Period:** %s\n\n", formatPeriod(r.StartDate, r.EndDate, r.location())))
 	sb.WriteString("---\n\n")
 
 	totalCommits := 0
@@ -57,6 +111,7 @@ func (r *Report) ToMarkdown() string {
 		totalCommits += len(rc.Commits)
 	}
 	sb.WriteString(fmt.Sprintf("**Total Commits:** %d\n\n", totalCommits))
+	sb.WriteString(r.StatsToMarkdown())
 
 	for _, rc := range r.Repos {
 		if len(rc.Commits) == 0 {
@@ -67,18 +122,57 @@ func (r *Report) ToMarkdown() string {
 		sb.WriteString(fmt.Sprintf("*%d commits*\n\n", len(rc.Commits)))
 
 		for _, c := range rc.Commits {
-			sb.WriteString(fmt.Sprintf("- **%s** - %s (`%s`)\n",
-				c.Date.Format("Jan 2"),
+			sb.WriteString(fmt.Sprintf("- **%s** - %s (`%s`)%s%s\n",
+				c.Date.In(r.location()).Format("Jan 2"),
 				c.Message,
 				c.Hash[:7],
+				coAuthorNote(c),
+				unmergedNote(c),
 			))
+			sb.WriteString(bodyToMarkdown(c.Body))
 		}
 		sb.WriteString("\n")
 	}
 
+	sb.WriteString(r.FailuresToMarkdown())
+
 	return sb.String()
 }
 
+// unmergedNote flags commits that only exist on branches other than the
+// main one.
+func unmergedNote(c repo.Commit) string {
+	if c.OnMainBranch || len(c.Branches) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" _(unmerged: %s)_", strings.Join(c.Branches, ", "))
+}
+
+// coAuthorNote flags commits the author only co-authored.
+func coAuthorNote(c repo.Commit) string {
+	if !c.CoAuthored {
+		return ""
+	}
+	return fmt.Sprintf(" _(co-author, with %s)_", c.Author)
+}
+
+// bodyToMarkdown quotes a commit body under its list item.
+func bodyToMarkdown(body string) string {
+	if body == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		sb.WriteString(strings.TrimRight("  > "+line, " ") + "\n")
+	}
+	return sb.String()
+}
+
+// ToJSON exports the report and its underlying commits, diffs included.
+func (r *Report) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
 func (r *Report) ToHTML() string {
 	md := r.ToMarkdown()
 	html := markdown.ToHTML([]byte(md), nil, nil)
@@ -0,0 +1,183 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+func TestToMarkdown_IncludesFailures(t *testing.T) {
+	r := NewReport(
+		config.UserConfig{FullName: "Test Author", Email: "test@example.com"},
+		time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	)
+	r.AddRepoCommits("good-repo", []repo.Commit{
+		{Hash: "abc1234567890", Message: "Add feature", Date: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)},
+	})
+	r.AddFailure("flaky-repo", "https://example.com/flaky.git", "clone: exit status 128")
+
+	md := r.ToMarkdown()
+
+	if !strings.Contains(md, "## good-repo") {
+		t.Error("Markdown should contain the readable repo")
+	}
+	if !strings.Contains(md, "## Repositories that could not be read") {
+		t.Error("Markdown should contain the failures section")
+	}
+	if !strings.Contains(md, "**flaky-repo** (https://example.com/flaky.git): clone: exit status 128") {
+		t.Errorf("Markdown should list the failing repo with its reason, got:\n%s", md)
+	}
+}
+
+func TestFailuresToMarkdown_NoFailures(t *testing.T) {
+	r := NewReport(config.UserConfig{}, time.Now(), time.Now())
+	if md := r.FailuresToMarkdown(); md != "" {
+		t.Errorf("Expected no failures section, got %q", md)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	r := NewReport(
+		config.UserConfig{FullName: "Test Author", Email: "test@example.com"},
+		time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	)
+	r.AddRepoCommits("good-repo", []repo.Commit{
+		{Hash: "abc1234567890", Message: "Add feature", Author: "Test Author", Content: "diff --git a/x b/x"},
+	})
+
+	data, err := r.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() failed: %v", err)
+	}
+
+	var decoded struct {
+		Author struct {
+			Email string `json:"email"`
+		} `json:"author"`
+		Repos []struct {
+			Repo    string `json:"repo"`
+			Commits []struct {
+				Hash string `json:"hash"`
+				Diff string `json:"diff"`
+			} `json:"commits"`
+		} `json:"repos"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("ToJSON() produced invalid JSON: %v", err)
+	}
+	if decoded.Author.Email != "test@example.com" {
+		t.Errorf("author email = %q", decoded.Author.Email)
+	}
+	if len(decoded.Repos) != 1 || decoded.Repos[0].Repo != "good-repo" || len(decoded.Repos[0].Commits) != 1 {
+		t.Fatalf("unexpected repos: %+v", decoded.Repos)
+	}
+	if c := decoded.Repos[0].Commits[0]; c.Hash != "abc1234567890" || c.Diff != "diff --git a/x b/x" {
+		t.Errorf("unexpected commit: %+v", c)
+	}
+}
+
+func TestToMarkdown_FlagsUnmergedCommits(t *testing.T) {
+	r := NewReport(config.UserConfig{FullName: "Test Author"}, time.Now(), time.Now())
+	r.AddRepoCommits("app", []repo.Commit{
+		{Hash: "abc1234567890", Message: "Merged work", Branches: []string{"main", "release"}, OnMainBranch: true},
+		{Hash: "def1234567890", Message: "Login work", Branches: []string{"feature/login"}},
+	})
+
+	md := r.ToMarkdown()
+	if !strings.Contains(md, "Login work (`def1234`) _(unmerged: feature/login)_") {
+		t.Errorf("unmerged commit should be flagged, got:\n%s", md)
+	}
+	if strings.Contains(md, "Merged work (`abc1234`) _") {
+		t.Errorf("merged commit should not be flagged, got:\n%s", md)
+	}
+}
+
+func TestToMarkdown_DiffStats(t *testing.T) {
+	r := NewReport(config.UserConfig{Email: "test@example.com"}, time.Now(), time.Now())
+	stats := func(files ...repo.FileStat) repo.CommitStats {
+		s := repo.CommitStats{FilesChanged: len(files), Files: files}
+		for _, f := range files {
+			s.Insertions += f.Insertions
+			s.Deletions += f.Deletions
+		}
+		return s
+	}
+	r.AddRepoCommits("api", []repo.Commit{
+		{Hash: "aaaaaaaaaa", Message: "one", Stats: stats(repo.FileStat{Path: "main.go", Insertions: 10, Deletions: 2}, repo.FileStat{Path: "go.mod", Insertions: 1})},
+		{Hash: "bbbbbbbbbb", Message: "two", Stats: stats(repo.FileStat{Path: "main.go", Insertions: 3, Deletions: 3})},
+	})
+	r.AddRepoCommits("web", []repo.Commit{
+		{Hash: "cccccccccc", Message: "three", Stats: stats(repo.FileStat{Path: "main.go", Insertions: 5})},
+	})
+
+	if r.Stats.Commits != 3 || r.Stats.FilesChanged != 3 || r.Stats.Insertions != 19 || r.Stats.Deletions != 5 {
+		t.Errorf("unexpected report totals: %+v", r.Stats)
+	}
+	if got := r.Repos[0].Stats; got.FilesChanged != 2 || got.Insertions != 14 || got.Deletions != 5 {
+		t.Errorf("unexpected repo totals: %+v", got)
+	}
+
+	files := r.Stats.MostTouchedFiles(2)
+	if len(files) != 2 || files[0].Path != "api/main.go" || files[0].Commits != 2 || files[1].Path != "web/main.go" {
+		t.Errorf("unexpected most touched files: %+v", files)
+	}
+
+	md := r.ToMarkdown()
+	for _, want := range []string{
+		"| api | 2 | 2 | +14 | -5 |",
+		"| **Total** | **3** | **3** | **+19** | **-5** |",
+		"1. `api/main.go` - 2 commits, +13 / -5",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown should contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestToMarkdown_BodyAndCoAuthor(t *testing.T) {
+	r := NewReport(config.UserConfig{Email: "jane@example.com"}, time.Now(), time.Now())
+	r.AddRepoCommits("api", []repo.Commit{
+		{Hash: "abc1234567890", Message: "Fix login redirect loop", Body: "The session cookie was dropped\n\non redirect.", Author: "Jane Doe"},
+		{Hash: "def1234567890", Message: "Pair on parser", Author: "John Roe", CoAuthored: true},
+	})
+
+	md := r.ToMarkdown()
+	for _, want := range []string{
+		"(`abc1234`)\n  > The session cookie was dropped\n  >\n  > on redirect.\n",
+		"Pair on parser (`def1234`) _(co-author, with John Roe)_\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown should contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestToMarkdown_UsesAuthorLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	r := NewReport(
+		config.UserConfig{FullName: "Test Author", Email: "test@example.com", Location: tokyo},
+		time.Date(2024, 1, 7, 15, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 14, 15, 0, 0, 0, time.UTC),
+	)
+	// late on the 9th in UTC is already the 10th in Tokyo
+	r.AddRepoCommits("api", []repo.Commit{
+		{Hash: "abc1234567890", Message: "Add feature", Date: time.Date(2024, 1, 9, 20, 0, 0, 0, time.UTC)},
+	})
+
+	md := r.ToMarkdown()
+
+	for _, want := range []string{
+		"**Period:** Jan 8, 2024 - Jan 14, 2024",
+		"- **Jan 10** - Add feature",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown should contain %q, got:\n%s", want, md)
+		}
+	}
+}
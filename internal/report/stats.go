@@ -0,0 +1,130 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+// mostTouchedFilesLimit caps the "most touched files" list in the markdown.
+const mostTouchedFilesLimit = 10
+
+// DiffStats aggregates the diff statistics of a set of commits, per
+// repository or for a whole report.
+type DiffStats struct {
+	Commits      int `json:"commits"`
+	FilesChanged int `json:"files_changed"` // distinct files
+	Insertions   int `json:"insertions"`
+	Deletions    int `json:"deletions"`
+
+	files map[string]*FileActivity
+}
+
+// FileActivity is how often, and how much, a file changed.
+type FileActivity struct {
+	Path       string
+	Commits    int
+	Insertions int
+	Deletions  int
+}
+
+func (s *DiffStats) addCommit(c repo.Commit) {
+	s.Commits++
+	s.Insertions += c.Stats.Insertions
+	s.Deletions += c.Stats.Deletions
+	for _, f := range c.Stats.Files {
+		s.file(f.Path).add(FileActivity{Commits: 1, Insertions: f.Insertions, Deletions: f.Deletions})
+	}
+}
+
+// merge adds other to s, prefixing its file paths so files from different
+// repositories stay apart.
+func (s *DiffStats) merge(prefix string, other DiffStats) {
+	s.Commits += other.Commits
+	s.Insertions += other.Insertions
+	s.Deletions += other.Deletions
+	for path, activity := range other.files {
+		s.file(prefix + path).add(*activity)
+	}
+}
+
+func (s *DiffStats) file(path string) *FileActivity {
+	if s.files == nil {
+		s.files = map[string]*FileActivity{}
+	}
+	f, ok := s.files[path]
+	if !ok {
+		f = &FileActivity{Path: path}
+		s.files[path] = f
+		s.FilesChanged++
+	}
+	return f
+}
+
+func (f *FileActivity) add(other FileActivity) {
+	f.Commits += other.Commits
+	f.Insertions += other.Insertions
+	f.Deletions += other.Deletions
+}
+
+// MostTouchedFiles returns up to limit files ordered by the number of
+// commits that changed them, then by lines changed.
+func (s DiffStats) MostTouchedFiles(limit int) []FileActivity {
+	files := make([]FileActivity, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		if la, lb := a.Insertions+a.Deletions, b.Insertions+b.Deletions; la != lb {
+			return la > lb
+		}
+		return a.Path < b.Path
+	})
+	if len(files) > limit {
+		files = files[:limit]
+	}
+	return files
+}
+
+// StatsToMarkdown renders the per-repository totals table and the files
+// touched most often, or an empty string when there are no commits.
+func (r *Report) StatsToMarkdown() string {
+	if r.Stats.Commits == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Totals\n\n")
+	sb.WriteString("| Repository | Commits | Files changed | Insertions | Deletions |\n")
+	sb.WriteString("|---|---:|---:|---:|---:|\n")
+	for _, rc := range r.Repos {
+		if rc.Stats.Commits == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | +%d | -%d |\n",
+			rc.RepoName, rc.Stats.Commits, rc.Stats.FilesChanged, rc.Stats.Insertions, rc.Stats.Deletions))
+	}
+	sb.WriteString(fmt.Sprintf("| **Total** | **%d** | **%d** | **+%d** | **-%d** |\n\n",
+		r.Stats.Commits, r.Stats.FilesChanged, r.Stats.Insertions, r.Stats.Deletions))
+
+	files := r.Stats.MostTouchedFiles(mostTouchedFilesLimit)
+	if len(files) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("### Most touched files\n\n")
+	for i, f := range files {
+		commits := "commits"
+		if f.Commits == 1 {
+			commits = "commit"
+		}
+		sb.WriteString(fmt.Sprintf("%d. `%s` - %d %s, +%d / -%d\n", i+1, f.Path, f.Commits, commits, f.Insertions, f.Deletions))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TeamReport covers several authors over the same period. Repositories that
+// could not be synced are shared by all members, so they live on the team
+// report; one that could not be read for a single member is on that
+// member's report. The
+// period is shown in the location of StartDate and each member's commits in
+// the member's own.
+type TeamReport struct {
+	StartDate time.Time       `json:"start_date"`
+	EndDate   time.Time       `json:"end_date"`
+	Members   []*MemberReport `json:"members"`
+	Failures  []RepoFailure   `json:"failures,omitempty"`
+}
+
+// MemberReport is one member's part of a team report. Summary holds the
+// generated summary for the member, if any.
+type MemberReport struct {
+	*Report
+	Summary string `json:"summary,omitempty"`
+}
+
+func NewTeamReport(startDate, endDate time.Time) *TeamReport {
+	return &TeamReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Members:   []*MemberReport{},
+	}
+}
+
+func (t *TeamReport) AddMember(r *Report, summary string) {
+	t.Members = append(t.Members, &MemberReport{Report: r, Summary: summary})
+}
+
+func (t *TeamReport) AddFailure(repoName, url, reason string) {
+	t.Failures = append(t.Failures, RepoFailure{
+		RepoName: repoName,
+		URL:      url,
+		Reason:   reason,
+	})
+}
+
+// RollupToMarkdown renders a table with the commits and active repositories
+// of every member, followed by the team totals. A commit shared by several
+// members, through a Co-authored-by trailer, counts once in the total.
+func (t *TeamReport) RollupToMarkdown() string {
+	var sb strings.Builder
+	sb.WriteString("## Team rollup\n\n")
+	sb.WriteString("| Member | Commits | Repositories |\n")
+	sb.WriteString("|---|---:|---:|\n")
+
+	teamCommits := map[string]bool{}
+	activeRepos := map[string]bool{}
+	for _, m := range t.Members {
+		commits, repos := m.activity()
+		for _, rc := range m.Repos {
+			for _, c := range rc.Commits {
+				teamCommits[rc.RepoName+"\x00"+c.Hash] = true
+			}
+		}
+		for _, name := range repos {
+			activeRepos[name] = true
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d |\n", memberName(m.Author.FullName, m.Author.Email), commits, len(repos)))
+	}
+	sb.WriteString(fmt.Sprintf("| **Total** | **%d** | **%d** |\n\n", len(teamCommits), len(activeRepos)))
+	return sb.String()
+}
+
+func (t *TeamReport) ToMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString("# Team Report\n\n")
+	sb.WriteString(fmt.Sprintf("**Period:** %s\n\n", formatPeriod(t.StartDate, t.EndDate, t.StartDate.Location())))
+	sb.WriteString("---\n\n")
+	sb.WriteString(t.RollupToMarkdown())
+	sb.WriteString(t.MembersToMarkdown())
+	sb.WriteString(t.FailuresToMarkdown())
+	return sb.String()
+}
+
+// MembersToMarkdown renders one section per member, starting with the
+// repositories that could not be read for them. A member's summary is used
+// when present, otherwise their commits are listed.
+func (t *TeamReport) MembersToMarkdown() string {
+	var sb strings.Builder
+	for _, m := range t.Members {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", memberName(m.Author.FullName, m.Author.Email)))
+		for _, f := range m.Failures {
+			sb.WriteString(fmt.Sprintf("*Could not read **%s** for this member: %s*\n\n", f.RepoName, f.Reason))
+		}
+		if m.Summary != "" {
+			sb.WriteString(demoteHeadings(strings.TrimSpace(m.Summary), 1))
+			sb.WriteString("\n\n")
+			continue
+		}
+		if commits, _ := m.activity(); commits == 0 {
+			sb.WriteString("No commits in this period.\n\n")
+			continue
+		}
+		for _, rc := range m.Repos {
+			for _, c := range rc.Commits {
+				sb.WriteString(fmt.Sprintf("- **%s** %s - %s (`%s`)%s%s\n",
+					rc.RepoName,
+					c.Date.In(m.location()).Format("Jan 2"),
+					c.Message,
+					c.Hash[:7],
+					coAuthorNote(c),
+					unmergedNote(c),
+				))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// FailuresToMarkdown renders the repositories that could not be read, or an
+// empty string when every repository was read.
+func (t *TeamReport) FailuresToMarkdown() string {
+	r := Report{Failures: t.Failures}
+	return r.FailuresToMarkdown()
+}
+
+// MemberFailuresToMarkdown renders the repositories that could not be read
+// for m, shared or its own, or an empty string when there are none.
+func (t *TeamReport) MemberFailuresToMarkdown(m *MemberReport) string {
+	r := Report{Failures: append(append([]RepoFailure(nil), t.Failures...), m.Failures...)}
+	return r.FailuresToMarkdown()
+}
+
+// ToJSON exports the team report and every member's commits.
+func (t *TeamReport) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// activity returns the member's commit count and the repositories they
+// committed to.
+func (r *Report) activity() (int, []string) {
+	commits := 0
+	var repos []string
+	for _, rc := range r.Repos {
+		if len(rc.Commits) == 0 {
+			continue
+		}
+		commits += len(rc.Commits)
+		repos = append(repos, rc.RepoName)
+	}
+	return commits, repos
+}
+
+func memberName(fullName, email string) string {
+	if fullName != "" {
+		return fullName
+	}
+	return email
+}
+
+// demoteHeadings pushes every markdown heading down by levels, so that a
+// standalone summary fits under a member's section.
+func demoteHeadings(md string, levels int) string {
+	lines := strings.Split(md, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			lines[i] = strings.Repeat("#", levels) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,131 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youssefM1999/report/internal/config"
+	"github.com/youssefM1999/report/internal/repo"
+)
+
+func newTestTeamReport() *TeamReport {
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	team := NewTeamReport(start, end)
+
+	jane := NewReport(config.UserConfig{FullName: "Jane Doe", Email: "jane@example.com"}, start, end)
+	jane.AddRepoCommits("api", []repo.Commit{
+		{Hash: "aaa1234567890", Message: "Add endpoint", Date: time.Date(2024, 1, 9, 9, 0, 0, 0, time.UTC)},
+		{Hash: "bbb1234567890", Message: "Fix endpoint", Date: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)},
+	})
+	jane.AddRepoCommits("web", []repo.Commit{
+		{Hash: "ccc1234567890", Message: "Style page", Date: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+	})
+	team.AddMember(jane, "## api\n\n### aaa1234 - Add endpoint\n- Added it")
+
+	joe := NewReport(config.UserConfig{Email: "joe@example.com"}, start, end)
+	joe.AddRepoCommits("api", []repo.Commit{
+		{Hash: "ddd1234567890", Message: "Refactor handler", Date: time.Date(2024, 1, 12, 9, 0, 0, 0, time.UTC)},
+	})
+	// co-authored with Jane
+	joe.AddRepoCommits("web", []repo.Commit{
+		{Hash: "ccc1234567890", Message: "Style page", Date: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+	})
+	team.AddMember(joe, "")
+
+	idle := NewReport(config.UserConfig{FullName: "Idle Ian"}, start, end)
+	idle.AddFailure("web", "https://example.com/web.git", "log: exit status 128")
+	team.AddMember(idle, "")
+
+	team.AddFailure("legacy", "https://example.com/legacy.git", "clone: exit status 128")
+	return team
+}
+
+func TestTeamReport_RollupToMarkdown(t *testing.T) {
+	md := newTestTeamReport().RollupToMarkdown()
+
+	for _, want := range []string{
+		"| Jane Doe | 3 | 2 |",
+		"| joe@example.com | 2 | 2 |",
+		"| Idle Ian | 0 | 0 |",
+		// the shared commit counts once
+		"| **Total** | **4** | **2** |",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("rollup should contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestTeamReport_ToMarkdown(t *testing.T) {
+	md := newTestTeamReport().ToMarkdown()
+
+	for _, want := range []string{
+		"# Team Report",
+		"**Period:** Jan 8, 2024 - Jan 14, 2024",
+		"## Jane Doe",
+		// the member's summary headings sit below the member heading
+		"### api\n\n#### aaa1234 - Add endpoint",
+		"## joe@example.com",
+		"- **api** Jan 12 - Refactor handler (`ddd1234`)",
+		"## Idle Ian\n\n*Could not read **web** for this member: log: exit status 128*\n\nNo commits in this period.",
+		"**legacy** (https://example.com/legacy.git): clone: exit status 128",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown should contain %q, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestTeamReport_MemberFailuresToMarkdown(t *testing.T) {
+	team := newTestTeamReport()
+
+	md := team.MemberFailuresToMarkdown(team.Members[2])
+	if strings.Count(md, "## Repositories that could not be read") != 1 ||
+		!strings.Contains(md, "**legacy**") || !strings.Contains(md, "**web** (https://example.com/web.git): log: exit status 128") {
+		t.Errorf("expected the shared and the member's failures in one section, got:\n%s", md)
+	}
+	if md := team.MemberFailuresToMarkdown(team.Members[0]); strings.Contains(md, "**web**") {
+		t.Errorf("another member's failure should not be listed, got:\n%s", md)
+	}
+}
+
+func TestTeamReport_ToJSON(t *testing.T) {
+	data, err := newTestTeamReport().ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() failed: %v", err)
+	}
+
+	var decoded struct {
+		Members []struct {
+			Author struct {
+				Email string `json:"email"`
+			} `json:"author"`
+			Repos   []RepoCommits `json:"repos"`
+			Summary string        `json:"summary"`
+		} `json:"members"`
+		Failures []RepoFailure `json:"failures"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("ToJSON() produced invalid JSON: %v", err)
+	}
+	if len(decoded.Members) != 3 || decoded.Members[0].Author.Email != "jane@example.com" {
+		t.Fatalf("unexpected members: %+v", decoded.Members)
+	}
+	if len(decoded.Members[0].Repos) != 2 || decoded.Members[0].Summary == "" {
+		t.Errorf("member should carry their commits and summary: %+v", decoded.Members[0])
+	}
+	if len(decoded.Failures) != 1 {
+		t.Errorf("failures = %+v", decoded.Failures)
+	}
+}
+
+func TestDemoteHeadings(t *testing.T) {
+	md := "# Title\ntext\n```\n# not a heading\n```\n## Sub"
+	want := "## Title\ntext\n```\n# not a heading\n```\n### Sub"
+	if got := demoteHeadings(md, 1); got != want {
+		t.Errorf("demoteHeadings() = %q, want %q", got, want)
+	}
+}
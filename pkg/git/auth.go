@@ -0,0 +1,211 @@
+package git
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// DefaultTokenUsername is sent with an HTTPS token when no user name is
+// configured. Hosts that authenticate by token accept any non-empty name.
+const DefaultTokenUsername = "x-access-token"
+
+// ErrAuthFailed is matched by errors from remote operations that failed
+// because the remote rejected or asked for credentials.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// Auth holds the credentials used to reach a remote. The zero value relies
+// on what git finds on its own, such as a running SSH agent or the user's
+// core.sshCommand, but like every Auth it never has git or an askpass
+// helper prompt: missing credentials fail with ErrAuthFailed.
+type Auth struct {
+	Username   string // sent with Token; DefaultTokenUsername when empty
+	Token      string // HTTPS token or password
+	SSHKey     string // private key file for SSH remotes
+	KnownHosts string // known_hosts file used instead of the user's one
+}
+
+func (a Auth) username() string {
+	if a.Username != "" {
+		return a.Username
+	}
+	return DefaultTokenUsername
+}
+
+// env returns the environment git needs to use the credentials without
+// ever prompting. The token travels in an http.extraHeader set through
+// GIT_CONFIG_* variables, so it shows up neither in the process list nor in
+// the repository's config. The ssh command is only replaced when a key or
+// known_hosts file is configured; otherwise the user's own command and ssh
+// configuration apply, with askpass prompts disabled.
+func (a Auth) env(environ []string) []string {
+	env := []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_ASKPASS=",
+		"SSH_ASKPASS=",
+		"SSH_ASKPASS_REQUIRE=never",
+		"GCM_INTERACTIVE=never",
+	}
+
+	if a.Token != "" {
+		// keep configuration passed the same way by the caller
+		count, _ := strconv.Atoi(lookupEnv(environ, "GIT_CONFIG_COUNT"))
+		credentials := base64.StdEncoding.EncodeToString([]byte(a.username() + ":" + a.Token))
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.extraHeader", count),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", count, credentials),
+		)
+	}
+
+	if a.SSHKey != "" || a.KnownHosts != "" {
+		env = append(env, "GIT_SSH_COMMAND="+a.sshCommand())
+	}
+	return append(environ, env...)
+}
+
+// sshCommand is the ssh invocation git uses for SSH remotes.
+func (a Auth) sshCommand() string {
+	args := []string{"ssh", "-o", "BatchMode=yes"}
+	if a.SSHKey != "" {
+		args = append(args, "-i", shellQuote(a.SSHKey), "-o", "IdentitiesOnly=yes")
+	}
+	if a.KnownHosts != "" {
+		args = append(args, "-o", shellQuote("UserKnownHostsFile="+a.KnownHosts), "-o", "StrictHostKeyChecking=yes")
+	}
+	return strings.Join(args, " ")
+}
+
+// method returns the go-git credentials for url, or nil to let go-git use
+// its defaults.
+func (a Auth) method(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		if a.Token == "" {
+			return nil, nil
+		}
+		return &githttp.BasicAuth{Username: a.username(), Password: a.Token}, nil
+	case "ssh":
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+		var method transport.AuthMethod
+		var helper *gitssh.HostKeyCallbackHelper
+		switch {
+		case a.SSHKey != "":
+			keys, err := gitssh.NewPublicKeysFromFile(user, a.SSHKey, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SSH key: %w", err)
+			}
+			method, helper = keys, &keys.HostKeyCallbackHelper
+		case a.KnownHosts != "":
+			agent, err := gitssh.NewSSHAgentAuth(user)
+			if err != nil {
+				return nil, fmt.Errorf("%w: no SSH key configured and no SSH agent: %w", ErrAuthFailed, err)
+			}
+			method, helper = agent, &agent.HostKeyCallbackHelper
+		default:
+			return nil, nil
+		}
+		if a.KnownHosts != "" {
+			callback, err := gitssh.NewKnownHostsCallback(a.KnownHosts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+			}
+			helper.HostKeyCallback = callback
+		}
+		return method, nil
+	}
+	return nil, nil
+}
+
+// originMethod returns the go-git credentials for the origin remote.
+func (a Auth) originMethod(repo *gogit.Repository) (transport.AuthMethod, error) {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return nil, err
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, errors.New("origin has no URL")
+	}
+	return a.method(urls[0])
+}
+
+// authMarkers are what git and ssh print when credentials are missing or
+// rejected, lowercased.
+var authMarkers = []string{
+	"authentication failed",
+	"could not read username",
+	"could not read password",
+	"terminal prompts disabled",
+	"invalid username or password",
+	"http basic: access denied",
+	"the requested url returned error: 401",
+	"the requested url returned error: 403",
+	"permission denied (publickey",
+	"host key verification failed",
+}
+
+// authFailure returns the stderr line explaining an authentication
+// failure, or "" when the failure has another cause.
+func authFailure(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		lower := strings.ToLower(line)
+		for _, marker := range authMarkers {
+			if strings.Contains(lower, marker) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+	return ""
+}
+
+// goGitAuthError marks go-git errors caused by credentials with
+// ErrAuthFailed.
+func goGitAuthError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		strings.Contains(msg, "unable to authenticate") ||
+		strings.Contains(msg, "knownhosts:") {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return err
+}
+
+func lookupEnv(environ []string, key string) string {
+	value := ""
+	for _, kv := range environ {
+		if v, ok := strings.CutPrefix(kv, key+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remoteEnviron is the environment of git commands that talk to a remote.
+func remoteEnviron(auth Auth) []string {
+	return auth.env(os.Environ())
+}
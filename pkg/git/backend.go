@@ -0,0 +1,134 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"time"
+)
+
+const (
+	BackendExec  = "exec"
+	BackendGoGit = "go-git"
+)
+
+// Log records returned by GetCommitsByAuthor and GetCoAuthoredCommits are
+// made of LogFields fields, each terminated by a NUL byte: the hash, the
+// author name, the author timestamp and the raw message. Git refuses NUL in
+// messages and identities, so any other content is safe in every field.
+const (
+	LogFields          = 4
+	LogFieldTerminator = "\x00"
+)
+
+var errNoAuthors = errors.New("no author patterns given")
+
+// CompileAuthorPattern compiles an author pattern the way git log
+// --extended-regexp --regexp-ignore-case reads it. Perl-style syntax that
+// POSIX extended regular expressions lack, such as \d, \b or (?:...), is
+// rejected rather than matched differently by each backend.
+func CompileAuthorPattern(pattern string) (*regexp.Regexp, error) {
+	if _, err := syntax.Parse(pattern, syntax.POSIX); err != nil {
+		return nil, fmt.Errorf("invalid author pattern %q (use POSIX extended syntax): %w", pattern, err)
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// RemoteBranchRef returns the full name of origin's remote-tracking branch.
+func RemoteBranchRef(branch string) string {
+	return "refs/remotes/origin/" + branch
+}
+
+// SyncOptions select what Backend.Sync fetches. The zero value fetches the
+// whole history of the main branch.
+type SyncOptions struct {
+	// AllBranches fetches every branch of origin instead of only the main
+	// one, pruning the ones deleted upstream.
+	AllBranches bool
+	// Filter makes the cache a partial clone, for example "blob:none";
+	// missing objects are then fetched when first read, without the Sync
+	// credentials, so callers must only set it for remotes git can reach on
+	// its own. Only the exec backend supports it.
+	Filter string
+	// Since limits the fetched history to the commits made after it, plus
+	// their parents so that every commit in range can be diffed. A cache
+	// synced later without it gets its full history back. Only the exec
+	// backend supports it.
+	Since time.Time
+}
+
+// Backend is the set of git operations the report needs. Every backend
+// produces byte-for-byte compatible log output so callers can parse it the
+// same way regardless of the implementation.
+type Backend interface {
+	// Sync creates or updates a bare cache of url in repoDir with git fetch
+	// --prune: branches land under refs/remotes/origin/ and HEAD points at
+	// branch. A cache whose origin is not url, or a working tree cloned
+	// into repoDir by earlier versions, is replaced. Operations that reach
+	// the remote use auth and never prompt; rejected credentials fail with
+	// ErrAuthFailed.
+	Sync(repoDir, url, branch string, opts SyncOptions, auth Auth) error
+	// RemoteBranches lists the branches of origin, without the "origin/"
+	// prefix, sorted by name.
+	RemoteBranches(repoDir string) ([]string, error)
+	// GetCommitsByAuthor returns one record for every commit reachable
+	// from rev ("" for HEAD) whose author matches any of the given patterns
+	// and which was committed at or after since and before until (a zero
+	// until has no bound), newest first. Patterns are POSIX extended
+	// regular expressions, see CompileAuthorPattern, matched against
+	// "Name <email>" after applying the repository's .mailmap; every commit
+	// is listed once.
+	GetCommitsByAuthor(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error)
+	// GetCoAuthoredCommits returns records in the same format for every
+	// commit reachable from rev and committed in the same window whose
+	// message has a line starting with "Co-authored-by:", ignoring case.
+	// Callers decide which co-authors they are interested in.
+	GetCoAuthoredCommits(repoDir, rev string, since, until time.Time) ([]byte, error)
+	// GetCommitContents returns the diff introduced by a commit relative to
+	// its first parent.
+	GetCommitContents(repoDir, hash string) (string, error)
+	// GetCommitStats returns the per-file statistics of the same diff in
+	// the format of git diff --numstat -z, with rename detection: one
+	// "added\tdeleted\tpath\x00" record per file, "added\tdeleted\t\x00old\x00new\x00"
+	// for renames, and "-" counts for binary files.
+	GetCommitStats(repoDir, hash string) ([]byte, error)
+}
+
+// NewBackend returns the backend registered under name. An empty name
+// selects the exec backend.
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case "", BackendExec:
+		return ExecBackend{}, nil
+	case BackendGoGit:
+		return GoGitBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown git backend %q (want %q or %q)", name, BackendExec, BackendGoGit)
+	}
+}
+
+// lastSecondBefore returns the last whole second before until. Commit times
+// have a one second resolution and git's --until is inclusive.
+func lastSecondBefore(until time.Time) time.Time {
+	last := until.Truncate(time.Second)
+	if last.Equal(until) {
+		last = last.Add(-time.Second)
+	}
+	return last
+}
+
+func formatLogRecord(hash, author string, timestamp int64, message string) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00", hash, author, timestamp, message)
+}
+
+func formatNumstatRecord(added, deleted int, binary bool, oldPath, path string) string {
+	counts := fmt.Sprintf("%d\t%d\t", added, deleted)
+	if binary {
+		counts = "-\t-\t"
+	}
+	if oldPath != "" {
+		return counts + "\x00" + oldPath + "\x00" + path + "\x00"
+	}
+	return counts + path + "\x00"
+}
@@ -0,0 +1,595 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type fixtureCommit struct {
+	name    string
+	email   string
+	when    time.Time
+	message string
+	file    string
+	content string
+}
+
+// newFixture creates a local repository on branch main with the given
+// commits, oldest first, and returns its path.
+func newFixture(t *testing.T, commits ...fixtureCommit) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := gogit.PlainInitWithOptions(dir, &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	if err != nil {
+		t.Fatalf("failed to init fixture: %v", err)
+	}
+	addFixtureCommits(t, repo, dir, commits...)
+	return dir
+}
+
+func addFixtureCommits(t *testing.T, repo *gogit.Repository, dir string, commits ...fixtureCommit) {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to open fixture worktree: %v", err)
+	}
+	for _, c := range commits {
+		if err := os.WriteFile(filepath.Join(dir, c.file), []byte(c.content), 0644); err != nil {
+			t.Fatalf("failed to write fixture file: %v", err)
+		}
+		if _, err := worktree.Add(c.file); err != nil {
+			t.Fatalf("failed to stage fixture file: %v", err)
+		}
+		sig := &object.Signature{Name: c.name, Email: c.email, When: c.when}
+		if _, err := worktree.Commit(c.message, &gogit.CommitOptions{Author: sig, Committer: sig}); err != nil {
+			t.Fatalf("failed to commit fixture: %v", err)
+		}
+	}
+}
+
+var fixtureHistory = []fixtureCommit{
+	{"Jane Doe", "jane@example.com", time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), "Old work", "old.txt", "old\n"},
+	{"Jane Doe", "jane@example.com", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), "Add readme", "README.md", "hello\n"},
+	{"Other Dev", "other@example.com", time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC), "Someone else", "other.txt", "other\n"},
+	{"Jane Doe", "jane@example.com", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC), "Split a|||b in subject\n\nBody line", "README.md", "hello\nworld\n"},
+}
+
+// splitRecords splits log output into records of LogFields fields.
+func splitRecords(t *testing.T, output []byte) [][]string {
+	t.Helper()
+	fields := strings.Split(string(output), LogFieldTerminator)
+	if fields[len(fields)-1] != "" || (len(fields)-1)%LogFields != 0 {
+		t.Fatalf("log output is not a sequence of %d-field records: %q", LogFields, output)
+	}
+	var records [][]string
+	for i := 0; i+LogFields < len(fields); i += LogFields {
+		records = append(records, fields[i:i+LogFields])
+	}
+	return records
+}
+
+func backends() map[string]Backend {
+	return map[string]Backend{
+		BackendExec:  ExecBackend{},
+		BackendGoGit: GoGitBackend{},
+	}
+}
+
+func TestBackendConformance(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			repoDir := filepath.Join(t.TempDir(), "clone")
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+			if !IsBareRepository(repoDir) || IsGitRepository(repoDir) {
+				t.Fatalf("Sync() did not create a bare repository at %s", repoDir)
+			}
+
+			output, err := backend.GetCommitsByAuthor(repoDir, "", []string{"jane@example.com"}, since, time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+			}
+			records := splitRecords(t, output)
+			if len(records) != 2 {
+				t.Fatalf("Expected 2 records, got %d: %q", len(records), output)
+			}
+
+			newest := records[0]
+			if len(newest) != 4 {
+				t.Fatalf("Expected 4 fields, got %d: %q", len(newest), newest)
+			}
+			if newest[1] != "Jane Doe" {
+				t.Errorf("author = %q, want %q", newest[1], "Jane Doe")
+			}
+			if newest[2] != "1709467200" {
+				t.Errorf("timestamp = %q, want %q", newest[2], "1709467200")
+			}
+			if want := "Split a|||b in subject\n\nBody line"; newest[3] != want {
+				t.Errorf("message = %q, want %q", newest[3], want)
+			}
+
+			contents, err := backend.GetCommitContents(repoDir, newest[0])
+			if err != nil {
+				t.Fatalf("GetCommitContents() failed: %v", err)
+			}
+			if !strings.Contains(contents, "diff --git a/README.md b/README.md") || !strings.Contains(contents, "+world") {
+				t.Errorf("GetCommitContents() returned an unexpected diff:\n%s", contents)
+			}
+
+			oldest := records[1]
+			contents, err = backend.GetCommitContents(repoDir, oldest[0])
+			if err != nil {
+				t.Fatalf("GetCommitContents() failed: %v", err)
+			}
+			if !strings.Contains(contents, "+hello") {
+				t.Errorf("GetCommitContents() returned an unexpected diff:\n%s", contents)
+			}
+
+			if _, err := backend.GetCommitContents(repoDir, "0000000000000000000000000000000000000000"); err == nil {
+				t.Error("GetCommitContents() should fail with invalid commit hash")
+			}
+		})
+	}
+}
+
+func TestBackendConformance_Update(t *testing.T) {
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			src := newFixture(t, fixtureHistory[:2]...)
+			repoDir := filepath.Join(t.TempDir(), "clone")
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+
+			srcRepo, err := gogit.PlainOpen(src)
+			if err != nil {
+				t.Fatalf("failed to open fixture: %v", err)
+			}
+			addFixtureCommits(t, srcRepo, src, fixtureHistory[3])
+
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() on existing cache failed: %v", err)
+			}
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Errorf("Sync() when up to date failed: %v", err)
+			}
+
+			output, err := backend.GetCommitsByAuthor(repoDir, "", []string{"jane@example.com"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+			}
+			if !strings.Contains(string(output), "Split a|||b in subject") {
+				t.Errorf("Fetched commit missing from log: %q", output)
+			}
+		})
+	}
+}
+
+func TestBackendConformance_ReplacesWorkingTreeClone(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			// the layout of caches written by earlier versions
+			repoDir := filepath.Join(t.TempDir(), "clone")
+			if _, err := gogit.PlainClone(repoDir, false, &gogit.CloneOptions{URL: src}); err != nil {
+				t.Fatalf("failed to clone fixture: %v", err)
+			}
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() over a working tree failed: %v", err)
+			}
+			if !IsBareRepository(repoDir) || IsGitRepository(repoDir) {
+				t.Errorf("Sync() should replace the working tree with a bare cache")
+			}
+			output, err := backend.GetCommitsByAuthor(repoDir, "", []string{"jane@example.com"}, since, time.Time{})
+			if err != nil || len(splitRecords(t, output)) != 2 {
+				t.Errorf("GetCommitsByAuthor() = %q, %v", output, err)
+			}
+
+			// anything else is not ours to remove
+			other := t.TempDir()
+			if err := os.WriteFile(filepath.Join(other, "notes.txt"), []byte("keep\n"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			if err := backend.Sync(other, src, "main", SyncOptions{}, Auth{}); err == nil {
+				t.Error("Sync() should refuse a directory that is not a cache")
+			}
+			if _, err := os.Stat(filepath.Join(other, "notes.txt")); err != nil {
+				t.Errorf("Sync() must not touch a directory that is not a cache: %v", err)
+			}
+
+			missing := filepath.Join(t.TempDir(), "missing-branch")
+			if err := backend.Sync(missing, src, "nope", SyncOptions{}, Auth{}); err == nil {
+				t.Error("Sync() should fail for a missing branch")
+			}
+			if _, err := os.Stat(missing); !os.IsNotExist(err) {
+				t.Errorf("a failed first Sync() should not leave a cache behind: %v", err)
+			}
+		})
+	}
+}
+
+func TestBackendConformance_SameLogOutput(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	outputs := map[string]string{}
+	for name, backend := range backends() {
+		output, err := backend.GetCommitsByAuthor(src, "", []string{"example.com"}, since, time.Time{})
+		if err != nil {
+			t.Fatalf("%s: GetCommitsByAuthor() failed: %v", name, err)
+		}
+		outputs[name] = string(output)
+	}
+	if outputs[BackendExec] != outputs[BackendGoGit] {
+		t.Errorf("Backends disagree:\nexec:\n%s\ngo-git:\n%s", outputs[BackendExec], outputs[BackendGoGit])
+	}
+}
+
+func TestBackendConformance_MultipleIdentitiesAndMailmap(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	src := newFixture(t,
+		fixtureCommit{"Jane Doe", "jane@example.com", day(1), "Add mailmap", ".mailmap", "Jane Doe <jane@example.com> <jane@personal.dev>\n"},
+		fixtureCommit{"jd", "jane@personal.dev", day(2), "From laptop", "a.txt", "a\n"},
+		fixtureCommit{"Jane Old", "123+jane@users.noreply.github.com", day(3), "From web UI", "b.txt", "b\n"},
+		fixtureCommit{"Other Dev", "other@example.com", day(4), "Someone else", "c.txt", "c\n"},
+	)
+	// both patterns match the first commit; it must still be listed once
+	authors := []string{`<jane@example\.com>`, `users\.noreply\.github\.com`, `^Jane Doe`}
+
+	outputs := map[string]string{}
+	for name, backend := range backends() {
+		output, err := backend.GetCommitsByAuthor(src, "", authors, day(0), time.Time{})
+		if err != nil {
+			t.Fatalf("%s: GetCommitsByAuthor() failed: %v", name, err)
+		}
+		outputs[name] = string(output)
+
+		records := splitRecords(t, output)
+		if len(records) != 3 {
+			t.Fatalf("%s: expected 3 records, got %d: %q", name, len(records), output)
+		}
+		for i, want := range []string{"Jane Old", "Jane Doe", "Jane Doe"} {
+			if got := records[i][1]; got != want {
+				t.Errorf("%s: record %d author = %q, want %q", name, i, got, want)
+			}
+		}
+	}
+	if outputs[BackendExec] != outputs[BackendGoGit] {
+		t.Errorf("Backends disagree:\nexec:\n%s\ngo-git:\n%s", outputs[BackendExec], outputs[BackendGoGit])
+	}
+
+	for name, backend := range backends() {
+		if _, err := backend.GetCommitsByAuthor(src, "", nil, day(0), time.Time{}); err == nil {
+			t.Errorf("%s: GetCommitsByAuthor() should fail without author patterns", name)
+		}
+	}
+}
+
+func TestBackendConformance_CoAuthoredCommits(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	src := newFixture(t,
+		fixtureCommit{"Other Dev", "other@example.com", day(1), "Pair on parser\n\nSplit the tokenizer out.\n\nCo-authored-by: Jane Doe <jane@example.com>\n", "a.txt", "a\n"},
+		fixtureCommit{"Other Dev", "other@example.com", day(2), "Solo work\n\nReviewed-by: Jane Doe <jane@example.com>\n", "b.txt", "b\n"},
+		fixtureCommit{"Third Dev", "third@example.com", day(3), "Mob session\n\nco-authored-by: Jane Doe <jane@example.com>", "c.txt", "c\n"},
+	)
+
+	outputs := map[string]string{}
+	for name, backend := range backends() {
+		output, err := backend.GetCoAuthoredCommits(src, "", day(0), time.Time{})
+		if err != nil {
+			t.Fatalf("%s: GetCoAuthoredCommits() failed: %v", name, err)
+		}
+		outputs[name] = string(output)
+
+		records := splitRecords(t, output)
+		if len(records) != 2 {
+			t.Fatalf("%s: expected 2 records, got %d: %q", name, len(records), output)
+		}
+		if !strings.HasPrefix(records[0][3], "Mob session") || !strings.HasSuffix(records[1][3], "Co-authored-by: Jane Doe <jane@example.com>\n") {
+			t.Errorf("%s: unexpected records: %q", name, records)
+		}
+	}
+	if outputs[BackendExec] != outputs[BackendGoGit] {
+		t.Errorf("Backends disagree:\nexec:\n%s\ngo-git:\n%s", outputs[BackendExec], outputs[BackendGoGit])
+	}
+}
+
+// checkoutFixtureBranch switches the fixture's worktree to branch, creating
+// it from the current HEAD when asked to.
+func checkoutFixtureBranch(t *testing.T, repo *gogit.Repository, branch string, create bool) {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to open fixture worktree: %v", err)
+	}
+	err = worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create})
+	if err != nil {
+		t.Fatalf("failed to check out %s: %v", branch, err)
+	}
+}
+
+func TestBackendConformance_RemoteBranches(t *testing.T) {
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			src := newFixture(t, fixtureHistory[:2]...)
+			srcRepo, err := gogit.PlainOpen(src)
+			if err != nil {
+				t.Fatalf("failed to open fixture: %v", err)
+			}
+			checkoutFixtureBranch(t, srcRepo, "feature/login", true)
+			addFixtureCommits(t, srcRepo, src, fixtureCommit{"Jane Doe", "jane@example.com", time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), "Unmerged login work", "login.txt", "login\n"})
+			checkoutFixtureBranch(t, srcRepo, "main", false)
+
+			repoDir := filepath.Join(t.TempDir(), "clone")
+			all := SyncOptions{AllBranches: true}
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+			if branches, _ := backend.RemoteBranches(repoDir); strings.Join(branches, ",") != "main" {
+				t.Errorf("only the main branch should be fetched by default, got %v", branches)
+			}
+
+			// a branch created and one deleted upstream after the clone
+			checkoutFixtureBranch(t, srcRepo, "spike", true)
+			checkoutFixtureBranch(t, srcRepo, "main", false)
+			if err := backend.Sync(repoDir, src, "main", all, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+			branches, err := backend.RemoteBranches(repoDir)
+			if err != nil {
+				t.Fatalf("RemoteBranches() failed: %v", err)
+			}
+			if strings.Join(branches, ",") != "feature/login,main,spike" {
+				t.Errorf("RemoteBranches() = %v", branches)
+			}
+
+			if err := srcRepo.Storer.RemoveReference(plumbing.NewBranchReferenceName("spike")); err != nil {
+				t.Fatalf("failed to delete fixture branch: %v", err)
+			}
+			if err := backend.Sync(repoDir, src, "main", all, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+			if branches, _ = backend.RemoteBranches(repoDir); strings.Join(branches, ",") != "feature/login,main" {
+				t.Errorf("Sync() should prune deleted branches, got %v", branches)
+			}
+
+			since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			head, err := backend.GetCommitsByAuthor(repoDir, "", []string{"jane@example.com"}, since, time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor(HEAD) failed: %v", err)
+			}
+			if strings.Contains(string(head), "Unmerged login work") {
+				t.Error("HEAD should not contain the feature branch commit")
+			}
+			feature, err := backend.GetCommitsByAuthor(repoDir, RemoteBranchRef("feature/login"), []string{"jane@example.com"}, since, time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor(feature) failed: %v", err)
+			}
+			if records := splitRecords(t, feature); len(records) != 2 || records[0][3] != "Unmerged login work" {
+				t.Errorf("feature branch log = %q", feature)
+			}
+			if _, err := backend.GetCommitsByAuthor(repoDir, RemoteBranchRef("missing"), []string{"jane@example.com"}, since, time.Time{}); err == nil {
+				t.Error("GetCommitsByAuthor() should fail for an unknown branch")
+			}
+		})
+	}
+}
+
+func TestBackendConformance_CommitStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	src := newFixture(t,
+		fixtureCommit{"Jane Doe", "jane@example.com", day(1), "Add notes", "notes.txt", "one\ntwo\nthree\nfour\nfive\nsix\n"},
+		fixtureCommit{"Jane Doe", "jane@example.com", day(2), "Add logo", "logo.bin", "\x00\x01\x02"},
+	)
+	repo, err := gogit.PlainOpen(src)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	worktree, _ := repo.Worktree()
+	if _, err := worktree.Move("notes.txt", "docs.txt"); err != nil {
+		t.Fatalf("failed to rename fixture file: %v", err)
+	}
+	addFixtureCommits(t, repo, src,
+		fixtureCommit{"Jane Doe", "jane@example.com", day(3), "Rename notes", "docs.txt", "one\ntwo\nthree\nfour\nfive\nsix\nseven\n"},
+	)
+	addFixtureCommits(t, repo, src,
+		fixtureCommit{"Jane Doe", "jane@example.com", day(4), "Edit docs", "docs.txt", "one\n2\nthree\nfour\nfive\nsix\nseven\n"},
+	)
+
+	output, err := ExecBackend{}.GetCommitsByAuthor(src, "", []string{"jane"}, day(0), time.Time{})
+	if err != nil {
+		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+	}
+	var hashes []string
+	for _, record := range splitRecords(t, output) {
+		hashes = append(hashes, record[0])
+	}
+
+	want := []string{
+		"1\t1\tdocs.txt\x00",
+		"1\t0\t\x00notes.txt\x00docs.txt\x00",
+		"-\t-\tlogo.bin\x00",
+		"6\t0\tnotes.txt\x00",
+	}
+	for name, backend := range backends() {
+		for i, hash := range hashes {
+			stats, err := backend.GetCommitStats(src, hash)
+			if err != nil {
+				t.Fatalf("%s: GetCommitStats() failed: %v", name, err)
+			}
+			if string(stats) != want[i] {
+				t.Errorf("%s: GetCommitStats(%s) = %q, want %q", name, hash[:7], stats, want[i])
+			}
+		}
+		if _, err := backend.GetCommitStats(src, "0000000000000000000000000000000000000000"); err == nil {
+			t.Errorf("%s: GetCommitStats() should fail with invalid commit hash", name)
+		}
+
+		// the rename is diffed as one, not as a deletion and an addition
+		patch, err := backend.GetCommitContents(src, hashes[1])
+		if err != nil {
+			t.Fatalf("%s: GetCommitContents() failed: %v", name, err)
+		}
+		if strings.Contains(patch, "-one") || !strings.Contains(patch, "+seven") {
+			t.Errorf("%s: GetCommitContents() should detect the rename, got:\n%s", name, patch)
+		}
+	}
+}
+
+func TestBackendConformance_Errors(t *testing.T) {
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			repoDir := filepath.Join(t.TempDir(), "clone")
+			if err := backend.Sync(repoDir, filepath.Join(t.TempDir(), "missing"), "main", SyncOptions{}, Auth{}); err == nil {
+				t.Error("Sync() should fail for a missing remote")
+			}
+			if _, err := backend.GetCommitsByAuthor("/nonexistent/directory", "", []string{"jane@example.com"}, time.Time{}, time.Time{}); err == nil {
+				t.Error("GetCommitsByAuthor() should fail with invalid repo directory")
+			}
+			if _, err := backend.GetCommitContents("/nonexistent/directory", "abc123"); err == nil {
+				t.Error("GetCommitContents() should fail with invalid repo directory")
+			}
+		})
+	}
+}
+
+func TestBackendConformance_PatternSyntax(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			output, err := backend.GetCommitsByAuthor(src, "", []string{`^[[:alpha:]]+ doe <jane@(example|test)\.com>$`}, since, time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+			}
+			if records := splitRecords(t, output); len(records) != 2 {
+				t.Errorf("expected jane's 2 commits, got %d", len(records))
+			}
+			// Perl-style syntax git log does not understand
+			for _, pattern := range []string{`\d`, `(?:jane)`, `(?i)jane`} {
+				if _, err := backend.GetCommitsByAuthor(src, "", []string{pattern}, since, time.Time{}); err == nil {
+					t.Errorf("GetCommitsByAuthor(%q) should reject the pattern", pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Backend
+		wantErr bool
+	}{
+		{"", ExecBackend{}, false},
+		{BackendExec, ExecBackend{}, false},
+		{BackendGoGit, GoGitBackend{}, false},
+		{"libgit2", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := NewBackend(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewBackend(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("NewBackend(%q) = %T, want %T", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBackendConformance_RecreatesCacheOfAnotherRemote(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for name, backend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			repoDir := filepath.Join(t.TempDir(), "cache")
+			other := newFixture(t, fixtureCommit{"Other Dev", "other@example.com", since.Add(time.Hour), "Unrelated", "x.txt", "x\n"})
+			if err := backend.Sync(repoDir, other, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() failed: %v", err)
+			}
+
+			src := newFixture(t, fixtureHistory...)
+			if IsCacheOf(repoDir, src) {
+				t.Fatal("IsCacheOf() should compare the origin URL")
+			}
+			if err := backend.Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+				t.Fatalf("Sync() with another URL failed: %v", err)
+			}
+			if !IsCacheOf(repoDir, src) {
+				t.Errorf("the cache should now fetch %s", src)
+			}
+			output, err := backend.GetCommitsByAuthor(repoDir, "", []string{"example.com"}, since, time.Time{})
+			if err != nil {
+				t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+			}
+			if strings.Contains(string(output), "Unrelated") || len(splitRecords(t, output)) != 3 {
+				t.Errorf("the old remote's history should be gone: %q", output)
+			}
+		})
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/user/repo.git":            "github.com/user/repo.git",
+		"https://x-access-token@GitHub.com/user/repo": "github.com/user/repo.git",
+		"git@github.com:user/repo.git":                "github.com/user/repo.git",
+		"ssh://git@example.com:2222/team/app.git/":    "example.com/team/app.git",
+		"/srv/git/app":                         "srv/git/app.git",
+		"file:///srv/git/app.git":              "srv/git/app.git",
+		"https://example.com/../../etc/passwd": "example.com/etc/passwd.git",
+		"https://example.com/a.git/b":          "example.com/a/b.git",
+		"https://example.com/my repo?x=1":      "example.com/my_repo_x_1.git",
+		"../..":                                "repo.git",
+	}
+	for url, want := range tests {
+		if got := CacheDir(url); got != want {
+			t.Errorf("CacheDir(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestBackendConformance_Until(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newest := time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := map[time.Time]int{
+		newest:                        1, // until is exclusive
+		newest.Add(time.Millisecond):  2,
+		newest.Add(-time.Millisecond): 1,
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC): 0,
+		{}: 2, // no bound
+	}
+	for until, want := range tests {
+		outputs := map[string]string{}
+		for name, backend := range backends() {
+			output, err := backend.GetCommitsByAuthor(src, "", []string{"jane@example.com"}, since, until)
+			if err != nil {
+				t.Fatalf("%s: GetCommitsByAuthor() failed: %v", name, err)
+			}
+			if got := len(splitRecords(t, output)); got != want {
+				t.Errorf("%s: until %v: expected %d records, got %d", name, until, want, got)
+			}
+			outputs[name] = string(output)
+		}
+		if outputs[BackendExec] != outputs[BackendGoGit] {
+			t.Errorf("Backends disagree:\nexec:\n%s\ngo-git:\n%s", outputs[BackendExec], outputs[BackendGoGit])
+		}
+	}
+}
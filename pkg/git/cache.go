@@ -0,0 +1,177 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CacheDir returns the relative directory caching url, derived from the
+// host and path of the URL: "https://github.com/user/repo.git" and
+// "git@github.com:user/repo" both give "github.com/user/repo.git". User
+// names, ports and schemes are dropped, characters that are unsafe in file
+// names are replaced, and "." or ".." segments are skipped, so the result
+// always stays below the directory it is joined to.
+func CacheDir(url string) string {
+	rest := url
+	if _, after, ok := strings.Cut(rest, "://"); ok {
+		rest = after
+	} else if colon, slash := strings.Index(rest, ":"), strings.Index(rest, "/"); colon > 0 && (slash < 0 || colon < slash) {
+		// scp-like syntax: [user@]host:path
+		rest = rest[:colon] + "/" + rest[colon+1:]
+	}
+
+	host, repoPath, _ := strings.Cut(rest, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	host, _, _ = strings.Cut(host, ":")
+
+	var segments []string
+	for _, s := range strings.Split(strings.ToLower(host)+"/"+repoPath, "/") {
+		// a cache never nests inside another one
+		s = sanitizeSegment(strings.TrimSuffix(s, ".git"))
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		segments = []string{"repo"}
+	}
+	return path.Join(segments...) + ".git"
+}
+
+// sanitizeSegment keeps letters, digits, '-', '_' and '.', replaces anything
+// else with '_', and drops "." and "..".
+func sanitizeSegment(s string) string {
+	if s == "." || s == ".." {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// IsCacheOf reports whether repoDir holds a bare cache whose origin is url.
+func IsCacheOf(repoDir, url string) bool {
+	if !IsBareRepository(repoDir) {
+		return false
+	}
+	repo, err := gogit.PlainOpen(repoDir)
+	if err != nil {
+		return false
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return false
+	}
+	urls := remote.Config().URLs
+	return len(urls) > 0 && urls[0] == url
+}
+
+// resetCache prepares repoDir for a new cache of url unless it already holds
+// one, and reports whether a new one must be created. A repository fetching
+// another URL, or a working tree cloned by earlier versions, is removed;
+// anything else is not ours to remove.
+func resetCache(repoDir, url string) (bool, error) {
+	if IsCacheOf(repoDir, url) {
+		return false, nil
+	}
+	if IsBareRepository(repoDir) || IsGitRepository(repoDir) {
+		if err := os.RemoveAll(repoDir); err != nil {
+			return false, fmt.Errorf("failed to remove stale cache: %w", err)
+		}
+	} else if entries, err := os.ReadDir(repoDir); err == nil && len(entries) > 0 {
+		return false, fmt.Errorf("%s exists and is not a repository cache", repoDir)
+	}
+	return true, nil
+}
+
+// CleanCloneOrigin returns the origin URL of the working tree in repoDir
+// when removing it loses nothing: no uncommitted or untracked changes, no
+// stash, and no commit that none of its remote branches hold. It returns ""
+// for anything else, or when in doubt.
+func CleanCloneOrigin(repoDir string) string {
+	if !IsGitRepository(repoDir) {
+		return ""
+	}
+	repo, err := gogit.PlainOpen(repoDir)
+	if err != nil {
+		return ""
+	}
+	remote, err := repo.Remote("origin")
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return ""
+	}
+	if _, err := repo.Reference(plumbing.ReferenceName("refs/stash"), false); err == nil {
+		return ""
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return ""
+	}
+	status, err := worktree.Status()
+	if err != nil || !status.IsClean() {
+		return ""
+	}
+
+	// every local branch and HEAD must be contained in a remote branch
+	refs, err := repo.References()
+	if err != nil {
+		return ""
+	}
+	var local, remotes []*object.Commit
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || !(ref.Name().IsBranch() || ref.Name().IsRemote()) {
+			return nil
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return err
+		}
+		if ref.Name().IsRemote() {
+			remotes = append(remotes, commit)
+		} else {
+			local = append(local, commit)
+		}
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	if head, err := repo.Head(); err == nil {
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return ""
+		}
+		local = append(local, commit)
+	}
+	for _, commit := range local {
+		pushed := false
+		for _, r := range remotes {
+			if commit.Hash == r.Hash {
+				pushed = true
+				break
+			}
+			if ok, err := commit.IsAncestor(r); err == nil && ok {
+				pushed = true
+				break
+			}
+		}
+		if !pushed {
+			return ""
+		}
+	}
+	return remote.Config().URLs[0]
+}
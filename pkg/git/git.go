@@ -1,40 +1,99 @@
 package git
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
-func Clone(repoDir, url, branch string) error {
-	// Check if the directory exists and is a git repository
-	// if it is, pull the repository
-	if IsGitRepository(repoDir) {
-		return Pull(repoDir, branch)
+// ExecBackend implements Backend by running the git binary found on PATH.
+type ExecBackend struct{}
+
+func (ExecBackend) Sync(repoDir, url, branch string, opts SyncOptions, auth Auth) error {
+	return Sync(repoDir, url, branch, opts, auth)
+}
+
+func (ExecBackend) RemoteBranches(repoDir string) ([]string, error) {
+	return RemoteBranches(repoDir)
+}
+
+func (ExecBackend) GetCommitsByAuthor(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error) {
+	return GetCommitsByAuthors(repoDir, rev, authors, since, until)
+}
+
+func (ExecBackend) GetCoAuthoredCommits(repoDir, rev string, since, until time.Time) ([]byte, error) {
+	return GetCoAuthoredCommits(repoDir, rev, since, until)
+}
+
+func (ExecBackend) GetCommitContents(repoDir, hash string) (string, error) {
+	return GetCommitContents(repoDir, hash)
+}
+
+func (ExecBackend) GetCommitStats(repoDir, hash string) ([]byte, error) {
+	return GetCommitStats(repoDir, hash)
+}
+
+// GetCommitsByAuthors lists the commits reachable from rev ("" for HEAD)
+// and committed in [since, until) whose mailmapped author matches any of the
+// given extended regular expressions, ignoring case. A zero until has no
+// bound.
+func GetCommitsByAuthors(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error) {
+	if len(authors) == 0 {
+		return nil, errNoAuthors
+	}
+	var filters []string
+	for _, author := range authors {
+		// git would fail on some patterns the other backend accepts, and
+		// match others differently
+		if _, err := CompileAuthorPattern(author); err != nil {
+			return nil, err
+		}
+		filters = append(filters, "--author", author)
 	}
+	return gitLog(repoDir, rev, since, until, filters...)
+}
 
-	// if it is not, clone the repository
-	cmd := exec.Command("git", "clone", "--branch", branch, url, repoDir)
-	return cmd.Run()
+// GetCoAuthoredCommits lists the commits reachable from rev ("" for HEAD)
+// and committed in [since, until) that carry a Co-authored-by trailer.
+func GetCoAuthoredCommits(repoDir, rev string, since, until time.Time) ([]byte, error) {
+	return gitLog(repoDir, rev, since, until, "--grep", "^Co-authored-by:")
 }
 
-func GetCommitsByAuthor(repoDir, email string, since time.Time) ([]byte, error) {
-	cmd := exec.Command("git", "-C", repoDir, "log",
-		"--format=%H|||%an|||%at|||%s",
+func gitLog(repoDir, rev string, since, until time.Time, filters ...string) ([]byte, error) {
+	// -z ends every record with NUL, which terminates the last field
+	args := []string{"-C", repoDir, "log", "-z",
+		"--format=%H%x00%aN%x00%at%x00%B",
 		"--since", since.Format(time.RFC3339),
-		"--author", email,
-	)
-	output, err := cmd.Output()
+		"--use-mailmap",
+		"--extended-regexp",
+		"--regexp-ignore-case",
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", lastSecondBefore(until).Format(time.RFC3339))
+	}
+	args = append(args, filters...)
+	if rev != "" {
+		args = append(args, rev)
+	}
+	output, err := run(exec.Command("git", append(args, "--")...))
 	if err != nil {
 		return nil, err
 	}
 	return output, nil
 }
 
+// GetCommitContents returns the patch of the commit against its first
+// parent, or the empty tree for a root commit, which works without a
+// working tree.
 func GetCommitContents(repoDir, hash string) (string, error) {
-	cmd := exec.Command("git", "-C", repoDir, "diff", hash+"^!", "--")
-	output, err := cmd.Output()
+	cmd := exec.Command("git", "-C", repoDir, "show", "--format=", "--no-color",
+		"--diff-merges=first-parent", hash, "--")
+	output, err := run(cmd)
 	if err != nil {
 		return "", err
 	}
@@ -42,13 +101,177 @@ func GetCommitContents(repoDir, hash string) (string, error) {
 	return string(output), nil
 }
 
+// GetCommitStats returns git's numstat output for the commit against its
+// first parent, or the empty tree for a root commit.
+func GetCommitStats(repoDir, hash string) ([]byte, error) {
+	cmd := exec.Command("git", "-C", repoDir, "show", "--format=", "--numstat", "-z", "-M",
+		"--diff-merges=first-parent", hash, "--")
+	return run(cmd)
+}
+
 func IsGitRepository(repoDir string) bool {
 	gitDir := filepath.Join(repoDir, ".git")
 	_, err := os.Stat(gitDir)
 	return err == nil
 }
 
-func Pull(repoDir, branch string) error {
-	cmd := exec.Command("git", "-C", repoDir, "pull", "origin", branch)
-	return cmd.Run()
+// Sync creates or updates the bare cache of url in repoDir. See
+// Backend.Sync.
+func Sync(repoDir, url, branch string, opts SyncOptions, auth Auth) error {
+	created, err := initCache(repoDir, url)
+	if err != nil {
+		return err
+	}
+	if err := syncCache(repoDir, branch, opts, auth); err != nil {
+		// do not leave an empty cache behind for the next run to trip on
+		if created {
+			os.RemoveAll(repoDir)
+		}
+		return err
+	}
+	return nil
+}
+
+// initCache makes repoDir a bare repository with url as origin, and reports
+// whether it had to create it.
+func initCache(repoDir, url string) (bool, error) {
+	create, err := resetCache(repoDir, url)
+	if err != nil || !create {
+		return false, err
+	}
+	if _, err := run(exec.Command("git", "init", "-q", "--bare", repoDir)); err != nil {
+		return false, err
+	}
+	if _, err := run(exec.Command("git", "-C", repoDir, "remote", "add", "origin", url)); err != nil {
+		os.RemoveAll(repoDir)
+		return false, err
+	}
+	return true, nil
+}
+
+func syncCache(repoDir, branch string, opts SyncOptions, auth Auth) error {
+	refspecs := []string{"+refs/heads/" + branch + ":" + RemoteBranchRef(branch)}
+	if opts.AllBranches {
+		refspecs = []string{"+refs/heads/*:" + RemoteBranchRef("*")}
+	}
+	fetch := func(args ...string) error {
+		args = append([]string{"-C", repoDir, "fetch", "-q", "--prune", "--no-tags"}, args...)
+		if opts.Filter != "" {
+			args = append(args, "--filter="+opts.Filter)
+		}
+		cmd := exec.Command("git", append(append(args, "origin"), refspecs...)...)
+		cmd.Env = remoteEnviron(auth)
+		_, err := run(cmd)
+		return err
+	}
+
+	switch {
+	case !opts.Since.IsZero():
+		err := fetch("--shallow-since=" + opts.Since.Format(time.RFC3339))
+		if errors.Is(err, errNoCommitsSince) {
+			// nothing happened in the range; the tips are still needed
+			err = fetch("--depth=1")
+		}
+		if err != nil {
+			return err
+		}
+		// the oldest commits in range need their parents to be diffed
+		if isShallow(repoDir) {
+			if err := fetch("--deepen=1"); err != nil {
+				return err
+			}
+		}
+	case isShallow(repoDir):
+		if err := fetch("--unshallow"); err != nil {
+			return err
+		}
+	default:
+		if err := fetch(); err != nil {
+			return err
+		}
+	}
+
+	// HEAD stands for the main branch, which also makes git read its
+	// .mailmap
+	ref := RemoteBranchRef(branch)
+	if _, err := run(exec.Command("git", "-C", repoDir, "rev-parse", "-q", "--verify", ref+"^{commit}")); err != nil {
+		return fmt.Errorf("branch %q not found on origin", branch)
+	}
+	_, err := run(exec.Command("git", "-C", repoDir, "symbolic-ref", "HEAD", ref))
+	return err
+}
+
+// IsBareRepository reports whether repoDir holds a bare repository, as used
+// for the repository cache.
+func IsBareRepository(repoDir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(repoDir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func isShallow(repoDir string) bool {
+	_, err := os.Stat(filepath.Join(repoDir, "shallow"))
+	return err == nil
+}
+
+func RemoteBranches(repoDir string) ([]string, error) {
+	cmd := exec.Command("git", "-C", repoDir, "for-each-ref",
+		"--format=%(refname:strip=3)", "--sort=refname", "refs/remotes/origin/")
+	output, err := run(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// origin/HEAD is a symbolic ref, not a branch
+		if line != "" && line != "HEAD" {
+			branches = append(branches, line)
+		}
+	}
+	return branches, nil
+}
+
+// errNoCommitsSince is returned by a shallow fetch when no commit of the
+// fetched branches is recent enough.
+var errNoCommitsSince = errors.New("no commits in range")
+
+// run executes cmd and returns its stdout. When git exits with an error the
+// last line it wrote to stderr is folded into the returned error, since the
+// exit status alone rarely says what went wrong. Failures caused by
+// credentials match ErrAuthFailed.
+//
+// Commands without an environment of their own never prompt either: reading
+// a partial clone can fetch missing objects from the remote.
+func run(cmd *exec.Cmd) ([]byte, error) {
+	if cmd.Env == nil {
+		cmd.Env = remoteEnviron(Auth{})
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if msg := authFailure(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s: %w", ErrAuthFailed, msg, err)
+			}
+			if strings.Contains(stderr.String(), "no commits selected for shallow requests") {
+				return nil, fmt.Errorf("%w: %w", errNoCommitsSince, err)
+			}
+			if msg := lastLine(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
 }
@@ -1,290 +1,179 @@
 package git
 
 import (
-	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 )
 
-func TestClone(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-clone-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
+func TestGetCommitsByAuthors(t *testing.T) {
+	repoDir := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	repoDir := filepath.Join(tmpDir, "test-repo")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	err = Clone(repoDir, url, branch)
+	output, err := GetCommitsByAuthors(repoDir, "", []string{"jane@example.com"}, since, time.Time{})
 	if err != nil {
-		t.Fatalf("Clone() failed: %v", err)
-	}
-
-	// Verify the repo was cloned by checking if .git directory exists
-	gitDir := filepath.Join(repoDir, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		t.Errorf("Clone() did not create .git directory at %s", gitDir)
-	}
-}
-
-func TestClone_InvalidURL(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-clone-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	repoDir := filepath.Join(tmpDir, "test-repo")
-	url := "https://invalid-url-that-does-not-exist.git"
-	branch := "master"
-
-	err = Clone(repoDir, url, branch)
-	if err == nil {
-		t.Error("Clone() should fail with invalid URL")
+		t.Fatalf("GetCommitsByAuthors() failed: %v", err)
 	}
-}
 
-func TestClone_ExistingDirectory(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-clone-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
+	records := splitRecords(t, output)
+	if len(records) != 2 {
+		t.Fatalf("expected the 2 commits by jane since 2024, got %d", len(records))
 	}
-	defer os.RemoveAll(tmpDir)
-
-	repoDir := filepath.Join(tmpDir, "test-repo")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	// First clone should succeed
-	err = Clone(repoDir, url, branch)
-	if err != nil {
-		t.Fatalf("First Clone() failed: %v", err)
+	parts := records[0]
+	if parts[0] == "" {
+		t.Error("Commit hash should not be empty")
 	}
-
-	// Verify the repo was cloned
-	gitDir := filepath.Join(repoDir, ".git")
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		t.Fatalf("First clone did not create .git directory at %s", gitDir)
+	if parts[1] != "Jane Doe" {
+		t.Errorf("Author name = %q, want %q", parts[1], "Jane Doe")
 	}
-
-	// Second clone into the same directory should pull instead of failing
-	err = Clone(repoDir, url, branch)
-	if err != nil {
-		t.Errorf("Clone() should pull when directory already exists, got error: %v", err)
+	if parts[2] == "" {
+		t.Error("Timestamp should not be empty")
 	}
-
-	// Verify the repo still exists after pull
-	if _, err := os.Stat(gitDir); os.IsNotExist(err) {
-		t.Errorf("Git directory should still exist after pull")
+	if !strings.HasPrefix(parts[3], "Split a|||b in subject") {
+		t.Errorf("Commit message = %q, want the newest commit first", parts[3])
 	}
 }
 
-func TestGetCommitsByAuthor(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-commits-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	// Clone the repo first
-	repoDir := filepath.Join(tmpDir, "hello-world")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	if err := Clone(repoDir, url, branch); err != nil {
-		t.Fatalf("Clone() failed: %v", err)
-	}
-
-	// Get commits by the known author (The Octocat)
-	email := "octocat@nowhere.com"
+func TestGetCommitsByAuthors_NoCommits(t *testing.T) {
+	repoDir := newFixture(t, fixtureHistory...)
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	output, err := GetCommitsByAuthor(repoDir, email, since)
+	output, err := GetCommitsByAuthors(repoDir, "", []string{"nonexistent@example.com"}, since, time.Time{})
 	if err != nil {
-		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+		t.Fatalf("GetCommitsByAuthors() should not fail for non-existent author, got: %v", err)
 	}
 
-	// Verify output format: should contain commit hash, author, timestamp, and message separated by |||
-	if len(output) > 0 {
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		if len(lines) == 0 {
-			t.Error("GetCommitsByAuthor() should return at least one commit")
-		}
-
-		// Check format of first commit
-		parts := strings.Split(lines[0], "|||")
-		if len(parts) != 4 {
-			t.Errorf("Expected 4 parts separated by |||, got %d: %v", len(parts), parts)
-		}
-
-		// Verify parts are not empty
-		if parts[0] == "" {
-			t.Error("Commit hash should not be empty")
-		}
-		if parts[1] == "" {
-			t.Error("Author name should not be empty")
-		}
-		if parts[2] == "" {
-			t.Error("Timestamp should not be empty")
-		}
-		if parts[3] == "" {
-			t.Error("Commit message should not be empty")
-		}
-	} else {
-		t.Log("No commits found for octocat@nowhere.com - this may be expected if the repo structure changed")
-	}
-}
-
-func TestGetCommitsByAuthor_NoCommits(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-commits-test-*")
-	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	// Clone the repo first
-	repoDir := filepath.Join(tmpDir, "hello-world")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	if err := Clone(repoDir, url, branch); err != nil {
-		t.Fatalf("Clone() failed: %v", err)
-	}
-
-	// Use an email that doesn't exist in the repo
-	email := "nonexistent@example.com"
-	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
-
-	output, err := GetCommitsByAuthor(repoDir, email, since)
-	if err != nil {
-		t.Fatalf("GetCommitsByAuthor() should not fail for non-existent author, got: %v", err)
-	}
-
-	// Output should be empty or just whitespace
 	if len(strings.TrimSpace(string(output))) != 0 {
 		t.Errorf("Expected empty output for non-existent author, got: %s", string(output))
 	}
 }
 
-func TestGetCommitsByAuthor_InvalidRepoDir(t *testing.T) {
+func TestGetCommitsByAuthors_InvalidRepoDir(t *testing.T) {
 	invalidDir := "/nonexistent/directory"
 	email := "test@example.com"
 	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	_, err := GetCommitsByAuthor(invalidDir, email, since)
+	_, err := GetCommitsByAuthors(invalidDir, "", []string{email}, since, time.Time{})
 	if err == nil {
-		t.Error("GetCommitsByAuthor() should fail with invalid repo directory")
+		t.Error("GetCommitsByAuthors() should fail with invalid repo directory")
 	}
 }
 
 func TestGetCommitContents(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-contents-test-*")
+	repoDir := newFixture(t, fixtureHistory...)
+	since := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	output, err := GetCommitsByAuthors(repoDir, "", []string{"jane"}, since, time.Time{})
 	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
+		t.Fatalf("GetCommitsByAuthors() failed: %v", err)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	// Clone the repo first
-	repoDir := filepath.Join(tmpDir, "hello-world")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	if err := Clone(repoDir, url, branch); err != nil {
-		t.Fatalf("Clone() failed: %v", err)
+	records := splitRecords(t, output)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 commit, got %d", len(records))
 	}
 
-	// Get a commit hash first
-	email := "octocat@nowhere.com"
-	since := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
-
-	output, err := GetCommitsByAuthor(repoDir, email, since)
+	contents, err := GetCommitContents(repoDir, records[0][0])
 	if err != nil {
-		t.Fatalf("GetCommitsByAuthor() failed: %v", err)
+		t.Fatalf("GetCommitContents() failed: %v", err)
 	}
-
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) == 0 || lines[0] == "" {
-		t.Skip("No commits found, skipping GetCommitContents test")
+	if !strings.Contains(contents, "diff --git a/README.md b/README.md") || !strings.Contains(contents, "+world") {
+		t.Errorf("expected the README.md patch, got:\n%s", contents)
 	}
+}
 
-	// Try to find a commit with non-empty content (skip merge commits)
-	var commitHash string
-	var contents string
-	var foundNonEmptyCommit bool
+func TestGetCommitContents_InvalidHash(t *testing.T) {
+	repoDir := newFixture(t, fixtureHistory...)
 
-	for _, line := range lines {
-		parts := strings.Split(line, "|||")
-		if len(parts) < 1 {
-			continue
-		}
-		commitHash = parts[0]
-		message := parts[3]
+	// Use an invalid commit hash
+	invalidHash := "0000000000000000000000000000000000000000"
 
-		// Skip merge commits as they often have empty diffs
-		if strings.HasPrefix(strings.ToLower(message), "merge") {
-			continue
-		}
+	_, err := GetCommitContents(repoDir, invalidHash)
+	if err == nil {
+		t.Error("GetCommitContents() should fail with invalid commit hash")
+	}
+}
+func TestGetCommitContents_InvalidRepoDir(t *testing.T) {
+	invalidDir := "/nonexistent/directory"
+	hash := "abc123"
 
-		// Get commit contents
-		var err error
-		contents, err = GetCommitContents(repoDir, commitHash)
-		if err != nil {
-			t.Logf("GetCommitContents() failed for commit %s: %v, trying next commit", commitHash[:7], err)
-			continue
-		}
+	_, err := GetCommitContents(invalidDir, hash)
+	if err == nil {
+		t.Error("GetCommitContents() should fail with invalid repo directory")
+	}
+}
 
-		// If we got content, we're done
-		if len(contents) > 0 {
-			foundNonEmptyCommit = true
-			break
+func TestSync_LimitsHistory(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	repoDir := filepath.Join(t.TempDir(), "cache")
+	revCount := func() string {
+		t.Helper()
+		output, err := run(exec.Command("git", "-C", repoDir, "rev-list", "--count", "HEAD"))
+		if err != nil {
+			t.Fatalf("rev-list failed: %v", err)
 		}
+		return strings.TrimSpace(string(output))
 	}
 
-	if !foundNonEmptyCommit {
-		t.Skip("No commits with non-empty content found (may be all merge commits)")
+	// the commits of March 2nd and 3rd, plus the parent of the oldest one
+	since := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	if err := Sync(repoDir, src, "main", SyncOptions{Since: since}, Auth{}); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
 	}
-
-	// Verify contents contain diff markers or git diff output
-	if !strings.Contains(contents, "diff") && !strings.Contains(contents, "@@") && !strings.Contains(contents, "index") {
-		t.Logf("Commit contents may not be in expected format, but got content of length %d", len(contents))
+	if !isShallow(repoDir) || revCount() != "3" {
+		t.Fatalf("expected a shallow cache of 3 commits, got %s", revCount())
 	}
-}
-
-func TestGetCommitContents_InvalidHash(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "git-contents-test-*")
+	output, err := GetCommitsByAuthors(repoDir, "", []string{"other@example.com"}, since, time.Time{})
 	if err != nil {
-		t.Fatalf("failed to create temp dir: %v", err)
+		t.Fatalf("GetCommitsByAuthors() failed: %v", err)
 	}
-	defer os.RemoveAll(tmpDir)
-
-	// Clone the repo first
-	repoDir := filepath.Join(tmpDir, "hello-world")
-	url := "https://github.com/octocat/Hello-World.git"
-	branch := "master"
-
-	if err := Clone(repoDir, url, branch); err != nil {
-		t.Fatalf("Clone() failed: %v", err)
+	records := splitRecords(t, output)
+	if len(records) != 1 {
+		t.Fatalf("expected the commit in range, got %q", output)
+	}
+	// diffed against its parent rather than an empty tree
+	if stats, err := GetCommitStats(repoDir, records[0][0]); err != nil || string(stats) != "1\t0\tother.txt\x00" {
+		t.Errorf("GetCommitStats() = %q, %v", stats, err)
 	}
 
-	// Use an invalid commit hash
-	invalidHash := "0000000000000000000000000000000000000000"
+	// nothing in range still leaves the branch readable
+	if err := Sync(repoDir, src, "main", SyncOptions{Since: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}, Auth{}); err != nil {
+		t.Fatalf("Sync() with an empty range failed: %v", err)
+	}
+	if _, err := GetCommitsByAuthors(repoDir, "", []string{"jane"}, since, time.Time{}); err != nil {
+		t.Errorf("GetCommitsByAuthors() failed: %v", err)
+	}
 
-	_, err = GetCommitContents(repoDir, invalidHash)
-	if err == nil {
-		t.Error("GetCommitContents() should fail with invalid commit hash")
+	if err := Sync(repoDir, src, "main", SyncOptions{}, Auth{}); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	if isShallow(repoDir) || revCount() != "4" {
+		t.Errorf("Sync() without a range should restore the full history, got %s commits", revCount())
 	}
 }
 
-func TestGetCommitContents_InvalidRepoDir(t *testing.T) {
-	invalidDir := "/nonexistent/directory"
-	hash := "abc123"
+func TestSync_PartialClone(t *testing.T) {
+	src := newFixture(t, fixtureHistory...)
+	if _, err := run(exec.Command("git", "-C", src, "config", "uploadpack.allowFilter", "true")); err != nil {
+		t.Fatalf("failed to allow filters: %v", err)
+	}
+	repoDir := filepath.Join(t.TempDir(), "cache")
+	if err := Sync(repoDir, "file://"+src, "main", SyncOptions{Filter: "blob:none"}, Auth{}); err != nil {
+		t.Fatalf("Sync() failed: %v", err)
+	}
+	output, err := run(exec.Command("git", "-C", repoDir, "config", "remote.origin.partialclonefilter"))
+	if err != nil || strings.TrimSpace(string(output)) != "blob:none" {
+		t.Fatalf("expected a partial clone, got %q, %v", output, err)
+	}
 
-	_, err := GetCommitContents(invalidDir, hash)
-	if err == nil {
-		t.Error("GetCommitContents() should fail with invalid repo directory")
+	// missing blobs are fetched when a diff needs them
+	output, err = GetCommitsByAuthors(repoDir, "", []string{"jane"}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil {
+		t.Fatalf("GetCommitsByAuthors() failed: %v", err)
+	}
+	contents, err := GetCommitContents(repoDir, splitRecords(t, output)[0][0])
+	if err != nil || !strings.Contains(contents, "+world") {
+		t.Errorf("GetCommitContents() = %q, %v", contents, err)
 	}
 }
@@ -0,0 +1,296 @@
+package git
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GoGitBackend implements Backend in pure Go on top of go-git, so it works
+// in environments without a git binary.
+type GoGitBackend struct{}
+
+// Sync fetches like the exec backend but ignores opts.Filter and
+// opts.Since, which go-git does not support: the cache always holds the full
+// history.
+func (GoGitBackend) Sync(repoDir, url, branch string, opts SyncOptions, auth Auth) error {
+	created, err := resetCache(repoDir, url)
+	if err != nil {
+		return err
+	}
+	var repo *gogit.Repository
+	if created {
+		repo, err = gogit.PlainInit(repoDir, true)
+		if err == nil {
+			_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{url}})
+		}
+	} else {
+		repo, err = gogit.PlainOpen(repoDir)
+	}
+	if err == nil {
+		err = syncGoGitCache(repo, branch, opts, auth)
+	}
+	if err != nil && created {
+		os.RemoveAll(repoDir)
+	}
+	return err
+}
+
+func syncGoGitCache(repo *gogit.Repository, branch string, opts SyncOptions, auth Auth) error {
+	method, err := auth.originMethod(repo)
+	if err != nil {
+		return err
+	}
+	refspec := config.RefSpec("+refs/heads/" + branch + ":" + RemoteBranchRef(branch))
+	if opts.AllBranches {
+		refspec = config.RefSpec("+refs/heads/*:" + RemoteBranchRef("*"))
+	}
+	err = repo.Fetch(&gogit.FetchOptions{
+		RemoteName: "origin",
+		Auth:       method,
+		RefSpecs:   []config.RefSpec{refspec},
+		Tags:       gogit.NoTags,
+		Prune:      true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return goGitAuthError(err)
+	}
+
+	ref := plumbing.ReferenceName(RemoteBranchRef(branch))
+	if _, err := repo.Reference(ref, true); err != nil {
+		return fmt.Errorf("branch %q not found on origin", branch)
+	}
+	return repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, ref))
+}
+
+func (GoGitBackend) RemoteBranches(repoDir string) ([]string, error) {
+	repo, err := gogit.PlainOpen(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return nil, err
+	}
+	defer refs.Close()
+
+	var branches []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		name, ok := strings.CutPrefix(ref.Name().String(), RemoteBranchRef(""))
+		if ok && name != "HEAD" && ref.Type() == plumbing.HashReference {
+			branches = append(branches, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(branches)
+	return branches, nil
+}
+
+func (GoGitBackend) GetCommitsByAuthor(repoDir, rev string, authors []string, since, until time.Time) ([]byte, error) {
+	if len(authors) == 0 {
+		return nil, errNoAuthors
+	}
+	// git log --author matches each pattern case-insensitively against the
+	// mailmapped "Name <email>" and keeps commits matching any of them
+	patterns := make([]*regexp.Regexp, len(authors))
+	for i, author := range authors {
+		pattern, err := CompileAuthorPattern(author)
+		if err != nil {
+			return nil, err
+		}
+		patterns[i] = pattern
+	}
+
+	return logRecords(repoDir, rev, since, until, func(c *object.Commit, ident string) bool {
+		return slices.ContainsFunc(patterns, func(p *regexp.Regexp) bool { return p.MatchString(ident) })
+	})
+}
+
+var coAuthoredBy = regexp.MustCompile(`(?im)^co-authored-by:`)
+
+func (GoGitBackend) GetCoAuthoredCommits(repoDir, rev string, since, until time.Time) ([]byte, error) {
+	return logRecords(repoDir, rev, since, until, func(c *object.Commit, _ string) bool {
+		return coAuthoredBy.MatchString(c.Message)
+	})
+}
+
+// logRecords walks the history from rev ("" for HEAD) like git log --since
+// --until and formats every commit accepted by match. match gets the commit
+// and its mailmapped "Name <email>".
+func logRecords(repoDir, rev string, since, until time.Time, match func(c *object.Commit, ident string) bool) ([]byte, error) {
+	repo, err := gogit.PlainOpen(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	mm, err := loadMailmap(repo, repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .mailmap: %w", err)
+	}
+	from := plumbing.ZeroHash
+	if rev != "" {
+		hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return nil, fmt.Errorf("unknown revision %q: %w", rev, err)
+		}
+		from = *hash
+	}
+	opts := &gogit.LogOptions{
+		From:  from,
+		Order: gogit.LogOrderCommitterTime,
+		Since: &since,
+	}
+	if !until.IsZero() {
+		last := lastSecondBefore(until)
+		opts.Until = &last
+	}
+	iter, err := repo.Log(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var sb strings.Builder
+	err = iter.ForEach(func(c *object.Commit) error {
+		name, email := mm.lookup(c.Author.Name, c.Author.Email)
+		if !match(c, fmt.Sprintf("%s <%s>", name, email)) {
+			return nil
+		}
+		sb.WriteString(formatLogRecord(c.Hash.String(), name, c.Author.When.Unix(), c.Message))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+func (GoGitBackend) GetCommitContents(repoDir, hash string) (string, error) {
+	parentTree, tree, err := commitTrees(repoDir, hash)
+	if err != nil {
+		return "", err
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return "", err
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return "", err
+	}
+	return patch.String(), nil
+}
+
+func (GoGitBackend) GetCommitStats(repoDir, hash string) ([]byte, error) {
+	parentTree, tree, err := commitTrees(repoDir, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		from, to        string
+		added, deleted  int
+		binary, renamed bool
+	}
+	var entries []entry
+	for _, fp := range patch.FilePatches() {
+		from, to := fp.Files()
+		e := entry{binary: fp.IsBinary()}
+		if from != nil {
+			e.from = from.Path()
+		}
+		if to != nil {
+			e.to = to.Path()
+		}
+		e.renamed = from != nil && to != nil && e.from != e.to
+		for _, chunk := range fp.Chunks() {
+			lines := countLines(chunk.Content())
+			switch chunk.Type() {
+			case diff.Add:
+				e.added += lines
+			case diff.Delete:
+				e.deleted += lines
+			}
+		}
+		entries = append(entries, e)
+	}
+	// git orders the entries by path
+	slices.SortFunc(entries, func(a, b entry) int {
+		return strings.Compare(cmp.Or(a.to, a.from), cmp.Or(b.to, b.from))
+	})
+
+	var sb strings.Builder
+	for _, e := range entries {
+		if e.renamed {
+			sb.WriteString(formatNumstatRecord(e.added, e.deleted, e.binary, e.from, e.to))
+		} else {
+			sb.WriteString(formatNumstatRecord(e.added, e.deleted, e.binary, "", cmp.Or(e.to, e.from)))
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+// commitTrees returns the tree of a commit and of its first parent. A root
+// commit is diffed against the empty tree, like git diff <hash>^!, so its
+// parent tree is nil.
+func commitTrees(repoDir, hash string) (*object.Tree, *object.Tree, error) {
+	repo, err := gogit.PlainOpen(repoDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, nil, fmt.Errorf("commit %s: %w", hash, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return parentTree, tree, nil
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	n := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// mailmap maps commit identities to canonical ones, following the format
+// described in gitmailmap(5):
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+type mailmap map[string]mailmapEntry
+
+type mailmapEntry struct {
+	name  string
+	email string
+}
+
+// mailmapKey identifies an entry. Entries without a commit name use an
+// empty name and match any name with that email.
+func mailmapKey(name, email string) string {
+	return strings.ToLower(email) + "\x00" + strings.ToLower(name)
+}
+
+func parseMailmap(r io.Reader) (mailmap, error) {
+	m := mailmap{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		properName, properEmail, rest, ok := cutIdentity(line)
+		if !ok {
+			continue
+		}
+		commitName, commitEmail, _, ok := cutIdentity(rest)
+		if !ok {
+			// "Proper Name <commit@email>" only fixes the name
+			commitName, commitEmail, properEmail = "", properEmail, ""
+		}
+
+		key := mailmapKey(commitName, commitEmail)
+		entry := m[key]
+		if properName != "" {
+			entry.name = properName
+		}
+		if properEmail != "" {
+			entry.email = properEmail
+		}
+		m[key] = entry
+	}
+	return m, scanner.Err()
+}
+
+// cutIdentity splits "Name <email> rest" into its parts.
+func cutIdentity(s string) (name, email, rest string, ok bool) {
+	before, after, found := strings.Cut(s, "<")
+	if !found {
+		return "", "", s, false
+	}
+	email, rest, found = strings.Cut(after, ">")
+	if !found {
+		return "", "", s, false
+	}
+	return strings.TrimSpace(before), strings.TrimSpace(email), strings.TrimSpace(rest), true
+}
+
+// lookup returns the canonical identity for name and email. Entries naming
+// the commit author take precedence over email-only ones.
+func (m mailmap) lookup(name, email string) (string, string) {
+	entry, ok := m[mailmapKey(name, email)]
+	if !ok {
+		entry, ok = m[mailmapKey("", email)]
+	}
+	if !ok {
+		return name, email
+	}
+	if entry.name != "" {
+		name = entry.name
+	}
+	if entry.email != "" {
+		email = entry.email
+	}
+	return name, email
+}
+
+// loadMailmap reads .mailmap from the working tree, or from HEAD when the
+// repository has no working tree. A repository without one gets an empty
+// mailmap.
+func loadMailmap(repo *gogit.Repository, repoDir string) (mailmap, error) {
+	f, err := os.Open(filepath.Join(repoDir, ".mailmap"))
+	if err == nil {
+		defer f.Close()
+		return parseMailmap(f)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return mailmap{}, nil
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	file, err := commit.File(".mailmap")
+	if errors.Is(err, object.ErrFileNotFound) {
+		return mailmap{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return parseMailmap(strings.NewReader(contents))
+}
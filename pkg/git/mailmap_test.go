@@ -0,0 +1,39 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMailmap(t *testing.T) {
+	input := `# canonical identities
+Jane Doe <jane@example.com>
+<jane@example.com> <jane@personal.dev>
+Jane Doe <jane@example.com> <JANE@OLD.ORG>
+Joe Bloggs <joe@example.com> Joe <bugs@example.com>
+
+not an entry
+`
+	m, err := parseMailmap(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseMailmap() failed: %v", err)
+	}
+
+	tests := []struct {
+		name, email         string
+		wantName, wantEmail string
+	}{
+		{"jd", "jane@example.com", "Jane Doe", "jane@example.com"},
+		{"jd", "jane@personal.dev", "jd", "jane@example.com"},
+		{"Old Jane", "jane@old.org", "Jane Doe", "jane@example.com"},
+		{"joe", "bugs@example.com", "Joe Bloggs", "joe@example.com"},
+		{"Someone", "bugs@example.com", "Someone", "bugs@example.com"},
+		{"Other", "other@example.com", "Other", "other@example.com"},
+	}
+	for _, tt := range tests {
+		name, email := m.lookup(tt.name, tt.email)
+		if name != tt.wantName || email != tt.wantEmail {
+			t.Errorf("lookup(%q, %q) = %q, %q, want %q, %q", tt.name, tt.email, name, email, tt.wantName, tt.wantEmail)
+		}
+	}
+}
@@ -1,18 +1,187 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func Retry(fn func() error, maxRetries int) error {
-	for i := range maxRetries {
-		if err := fn(); err != nil {
-			time.Sleep(time.Duration(math.Pow(2, float64(i))) * time.Second)
-			continue
-		}
+// Policy controls how often and how long Do waits between attempts.
+type Policy struct {
+	MaxAttempts int           // total attempts, including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled after each attempt
+	MaxDelay    time.Duration // upper bound for a single delay, Retry-After included; 0 means no bound
+	Jitter      float64       // fraction of each delay that is randomised, between 0 and 1
+
+	// Retryable classifies errors; returning false stops retrying at once.
+	// When nil every error is retried unless it is marked Permanent.
+	Retryable func(error) bool
+}
+
+// DefaultPolicy is a reasonable policy for calls to remote APIs.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// Error is returned once all attempts are used up or retrying stopped early.
+// It wraps the cause of the last failed attempt.
+type Error struct {
+	Attempts int
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed to execute function after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err so that Do returns it without retrying, whatever the
+// policy's classifier says.
+func Permanent(err error) error {
+	if err == nil {
 		return nil
 	}
-	return fmt.Errorf("failed to execute function after %d attempts", maxRetries)
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// RetryAfterer is implemented by errors that know when the server is willing
+// to take the next attempt, typically from an HTTP Retry-After header.
+type RetryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// Do calls fn until it succeeds, the policy gives up, or ctx is done. Between
+// attempts it waits for the exponential backoff delay, or for the delay an
+// error asks for through RetryAfterer when that is longer, but never for
+// more than MaxDelay.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context) error) error {
+	if policy.MaxAttempts <= 0 {
+		return &Error{Attempts: 0, Err: errors.New("no attempts allowed")}
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return stopped(ctx, attempt-1, lastErr)
+		}
+
+		lastErr = fn(ctx)
+		if lastErr == nil {
+			return nil
+		}
+		if IsPermanent(lastErr) || (policy.Retryable != nil && !policy.Retryable(lastErr)) {
+			return &Error{Attempts: attempt, Err: lastErr}
+		}
+		if attempt >= policy.MaxAttempts {
+			return &Error{Attempts: attempt, Err: lastErr}
+		}
+
+		delay := policy.backoff(attempt)
+		var ra RetryAfterer
+		if errors.As(lastErr, &ra) && ra.RetryAfter() > delay {
+			delay = ra.RetryAfter()
+			if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+				delay = policy.MaxDelay
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return stopped(ctx, attempt, lastErr)
+		case <-timer.C:
+		}
+	}
+}
+
+// stopped reports a cancelled retry loop. The error matches both the
+// context error and the last cause.
+func stopped(ctx context.Context, attempts int, lastErr error) error {
+	if lastErr == nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w: %w", ctx.Err(), &Error{Attempts: attempts, Err: lastErr})
+}
+
+// backoff returns the delay after the given attempt (1-based).
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		jitter := min(p.Jitter, 1)
+		spread := float64(delay) * jitter
+		delay = time.Duration(float64(delay) - spread + rand.Float64()*2*spread)
+	}
+	return delay
+}
+
+// Retry calls fn up to maxRetries times with a plain exponential backoff
+// starting at one second.
+func Retry(fn func() error, maxRetries int) error {
+	return Do(context.Background(), Policy{MaxAttempts: maxRetries, BaseDelay: time.Second}, func(context.Context) error {
+		return fn()
+	})
+}
+
+// RetryableStatus reports whether a request that failed with the given HTTP
+// status code is worth repeating.
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return code >= 500
+}
+
+// ParseRetryAfter parses the value of an HTTP Retry-After header, either a
+// number of seconds or an HTTP date. It returns 0 when the header is absent,
+// malformed or in the past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
 }
@@ -1,7 +1,9 @@
 package retry
 
 import (
+	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -56,7 +58,7 @@ func TestRetry_FailureAfterMaxRetries(t *testing.T) {
 	if attempts != expectedAttempts {
 		t.Errorf("Expected %d attempts, got %d", expectedAttempts, attempts)
 	}
-	expectedError := "failed to execute function after 3 attempts"
+	expectedError := "failed to execute function after 3 attempts: persistent error"
 	if err.Error() != expectedError {
 		t.Errorf("Expected error message '%s', got '%s'", expectedError, err.Error())
 	}
@@ -129,3 +131,190 @@ func TestRetry_OneRetry(t *testing.T) {
 		t.Errorf("Expected 1 attempt, got %d", attempts)
 	}
 }
+
+// fastPolicy retries quickly so the tests don't wait on real backoff.
+func fastPolicy(attempts int) Policy {
+	return Policy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func TestDo_WrapsLastCause(t *testing.T) {
+	cause := errors.New("third failure")
+	attempts := 0
+	err := Do(context.Background(), fastPolicy(3), func(context.Context) error {
+		attempts++
+		if attempts == 3 {
+			return cause
+		}
+		return errors.New("earlier failure")
+	})
+
+	var retryErr *Error
+	if !errors.As(err, &retryErr) || retryErr.Attempts != 3 {
+		t.Fatalf("Do() should return *Error after 3 attempts, got: %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Do() should wrap the last cause, got: %v", err)
+	}
+}
+
+func TestDo_PermanentErrorStopsImmediately(t *testing.T) {
+	cause := errors.New("bad request")
+	attempts := 0
+	err := Do(context.Background(), fastPolicy(5), func(context.Context) error {
+		attempts++
+		return Permanent(cause)
+	})
+
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt for a permanent error, got %d", attempts)
+	}
+	if !errors.Is(err, cause) || !IsPermanent(err) {
+		t.Errorf("Do() should return the permanent cause, got: %v", err)
+	}
+}
+
+func TestDo_ClassifierStopsRetrying(t *testing.T) {
+	errFatal := errors.New("fatal")
+	policy := fastPolicy(5)
+	policy.Retryable = func(err error) bool { return !errors.Is(err, errFatal) }
+
+	attempts := 0
+	err := Do(context.Background(), policy, func(context.Context) error {
+		attempts++
+		if attempts == 2 {
+			return errFatal
+		}
+		return errors.New("transient")
+	})
+
+	if attempts != 2 {
+		t.Errorf("Expected retrying to stop at the unretryable error, got %d attempts", attempts)
+	}
+	if !errors.Is(err, errFatal) {
+		t.Errorf("Do() should wrap the unretryable error, got: %v", err)
+	}
+}
+
+type retryAfterErr struct{ delay time.Duration }
+
+func (e retryAfterErr) Error() string             { return "rate limited" }
+func (e retryAfterErr) RetryAfter() time.Duration { return e.delay }
+
+func TestDo_HonoursRetryAfter(t *testing.T) {
+	policy := fastPolicy(2)
+	policy.MaxDelay = time.Second
+	var times []time.Time
+	err := Do(context.Background(), policy, func(context.Context) error {
+		times = append(times, time.Now())
+		if len(times) == 1 {
+			return retryAfterErr{delay: 150 * time.Millisecond}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	if waited := times[1].Sub(times[0]); waited < 150*time.Millisecond {
+		t.Errorf("Do() should wait for Retry-After, waited %v", waited)
+	}
+}
+
+func TestDo_CapsRetryAfterAtMaxDelay(t *testing.T) {
+	policy := fastPolicy(2)
+	policy.MaxDelay = 20 * time.Millisecond
+	start := time.Now()
+	attempts := 0
+	err := Do(context.Background(), policy, func(context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return retryAfterErr{delay: time.Hour}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() failed: %v", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Do() should wait at most MaxDelay, waited %v", waited)
+	}
+}
+
+func TestDo_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cause := errors.New("unavailable")
+	policy := Policy{MaxAttempts: 5, BaseDelay: time.Hour}
+
+	attempts := 0
+	done := make(chan error)
+	go func() {
+		done <- Do(ctx, policy, func(context.Context) error {
+			attempts++
+			return cause
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) || !errors.Is(err, cause) {
+			t.Errorf("Do() should report cancellation and the last cause, got: %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("Expected 1 attempt before cancellation, got %d", attempts)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Do() did not return after the context was cancelled")
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := Policy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.backoff(2); got < time.Second || got > 3*time.Second {
+			t.Fatalf("jittered backoff(2) = %v, want within [1s, 3s]", got)
+		}
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := map[int]bool{
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusNotFound:            false,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+	}
+	for code, want := range tests {
+		if got := RetryableStatus(code); got != want {
+			t.Errorf("RetryableStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-3":                            0,
+		"soon":                          0,
+		"Mon, 06 Jan 2025 12:00:30 GMT": 30 * time.Second,
+		"Mon, 06 Jan 2025 11:00:00 GMT": 0,
+	}
+	for value, want := range tests {
+		if got := ParseRetryAfter(value, now); got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}